@@ -0,0 +1,441 @@
+package internal
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
+	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/injection/clients/dynamicclient"
+)
+
+// User is the identity of an authenticated caller of the network transports.
+type User struct {
+	Name   string
+	UID    string
+	Groups []string
+	Extra  map[string][]string
+}
+
+// Authenticator validates bearer tokens. It returns a nil User if the token is not valid.
+type Authenticator interface {
+	AuthenticateToken(ctx context.Context, token string) (*User, error)
+}
+
+type userKey struct{}
+
+// UserFromContext returns the authenticated caller, or nil if the request was not authenticated.
+func UserFromContext(ctx context.Context) *User {
+	if u, ok := ctx.Value(userKey{}).(*User); ok {
+		return u
+	}
+	return nil
+}
+
+// RequireBearerToken is an HTTP middleware that rejects the requests without a valid bearer token
+// and stores the authenticated caller in the request context.
+func RequireBearerToken(next http.Handler, authenticator Authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tekton-mcp"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := authenticator.AuthenticateToken(r.Context(), token)
+		if err != nil {
+			slog.Error(fmt.Sprintf("failed to authenticate token: %v", err))
+			http.Error(w, "Failed to authenticate token", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tekton-mcp", error="invalid_token"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// StaticTokenAuthenticator authenticates tokens listed in a file using the format of the
+// kube-apiserver --token-auth-file: token,user,uid,"group1,group2" on each line.
+type StaticTokenAuthenticator struct {
+	tokens map[string]*User
+}
+
+// NewStaticTokenAuthenticator reads the tokens from the given file.
+func NewStaticTokenAuthenticator(path string) (*StaticTokenAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", path, err)
+	}
+
+	tokens := map[string]*User{}
+	for i, record := range records {
+		if len(record) < 3 {
+			return nil, fmt.Errorf("token file %s, line %d: expected at least token, user and uid", path, i+1)
+		}
+		user := &User{Name: record[1], UID: record[2]}
+		if len(record) > 3 && record[3] != "" {
+			user.Groups = strings.Split(record[3], ",")
+		}
+		tokens[record[0]] = user
+	}
+	return &StaticTokenAuthenticator{tokens: tokens}, nil
+}
+
+func (a *StaticTokenAuthenticator) AuthenticateToken(_ context.Context, token string) (*User, error) {
+	return a.tokens[token], nil
+}
+
+// TokenReviewAuthenticator authenticates tokens with a Kubernetes TokenReview.
+type TokenReviewAuthenticator struct {
+	client    kubernetes.Interface
+	audiences []string
+}
+
+// NewTokenReviewAuthenticator creates an authenticator reviewing tokens with the given client,
+// optionally requiring one of the given audiences.
+func NewTokenReviewAuthenticator(client kubernetes.Interface, audiences []string) *TokenReviewAuthenticator {
+	return &TokenReviewAuthenticator{client: client, audiences: audiences}
+}
+
+func (a *TokenReviewAuthenticator) AuthenticateToken(ctx context.Context, token string) (*User, error) {
+	review, err := a.client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: a.audiences,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TokenReview: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, nil
+	}
+
+	user := &User{
+		Name:   review.Status.User.Username,
+		UID:    review.Status.User.UID,
+		Groups: review.Status.User.Groups,
+	}
+	if len(review.Status.User.Extra) > 0 {
+		user.Extra = map[string][]string{}
+		for k, v := range review.Status.User.Extra {
+			user.Extra[k] = v
+		}
+	}
+	return user, nil
+}
+
+// Authenticators tries each of its authenticators in order until one accepts the token.
+type Authenticators []Authenticator
+
+func (as Authenticators) AuthenticateToken(ctx context.Context, token string) (*User, error) {
+	var errs []error
+	for _, a := range as {
+		user, err := a.AuthenticateToken(ctx, token)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if user != nil {
+			return user, nil
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// impersonatedClients are the clients acting as an authenticated caller.
+type impersonatedClients struct {
+	kube     kubernetes.Interface
+	pipeline versioned.Interface
+	dynamic  dynamic.Interface
+}
+
+// impersonatedClientsTTL is how long the clients impersonating a caller are kept without calls.
+const impersonatedClientsTTL = 10 * time.Minute
+
+// WithImpersonation returns an HTTPContextFunc that replaces the Kubernetes and Tekton clients of the
+// context with clients impersonating the authenticated caller, so that cluster RBAC is enforced per caller.
+func WithImpersonation(cfg *rest.Config, next HTTPContextFunc) HTTPContextFunc {
+	cache := newClientsCache(impersonatedClientsTTL)
+
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if next != nil {
+			ctx = next(ctx, r)
+		}
+		user := UserFromContext(ctx)
+		if user == nil {
+			return ctx
+		}
+
+		key := fmt.Sprintf("%s\x00%s\x00%s\x00%v", user.Name, user.UID, strings.Join(user.Groups, ","), user.Extra)
+		c, err := cache.get(key, time.Now(), func() (*impersonatedClients, error) {
+			return newImpersonatedClients(cfg, user)
+		})
+		if err != nil {
+			// Keep the server clients out of reach: the authorization checks and the clients fail
+			err = fmt.Errorf("failed to create clients impersonating %s: %w", user.Name, err)
+			slog.Error(err.Error())
+			return withImpersonationError(ctx, err)
+		}
+		return withClients(ctx, c)
+	}
+}
+
+// clientsCache keeps the clients impersonating each caller, and drops the clients that were not
+// used for its TTL so that the callers seen over the life of the server don't accumulate.
+type clientsCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*cachedClients
+	lastSweep time.Time
+}
+
+type cachedClients struct {
+	clients  *impersonatedClients
+	lastUsed time.Time
+}
+
+func newClientsCache(ttl time.Duration) *clientsCache {
+	return &clientsCache{ttl: ttl, entries: map[string]*cachedClients{}}
+}
+
+// get returns the clients of the key, created with create if they are not cached.
+func (c *clientsCache) get(key string, now time.Time, create func() (*impersonatedClients, error)) (*impersonatedClients, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The expired entries are swept at most once per TTL
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for k, entry := range c.entries {
+			if now.Sub(entry.lastUsed) >= c.ttl {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	entry, ok := c.entries[key]
+	if !ok || now.Sub(entry.lastUsed) >= c.ttl {
+		clients, err := create()
+		if err != nil {
+			return nil, err
+		}
+		entry = &cachedClients{clients: clients}
+		c.entries[key] = entry
+	}
+	entry.lastUsed = now
+	return entry.clients, nil
+}
+
+type impersonationErrorKey struct{}
+
+// withImpersonationError marks the context of a caller that could not be impersonated, and replaces its
+// clients with clients failing every request, so that nothing runs with the server identity.
+func withImpersonationError(ctx context.Context, err error) context.Context {
+	ctx = context.WithValue(ctx, impersonationErrorKey{}, err)
+	failing := &rest.Config{Host: "https://impersonation.invalid", Transport: failingTransport{err: err}}
+	c, cerr := newClients(failing)
+	if cerr != nil {
+		// authorize rejects every call of the context, the server clients are never reached
+		slog.Error(fmt.Sprintf("failed to create failing clients: %v", cerr))
+		return ctx
+	}
+	return withClients(ctx, c)
+}
+
+// failingTransport fails every request with the error that prevented the impersonation.
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+// withClients replaces the clients of the context.
+func withClients(ctx context.Context, c *impersonatedClients) context.Context {
+	ctx = context.WithValue(ctx, kubeclient.Key{}, c.kube)
+	ctx = context.WithValue(ctx, pipelineclient.Key{}, c.pipeline)
+	ctx = context.WithValue(ctx, dynamicclient.Key{}, c.dynamic)
+	return ctx
+}
+
+func newImpersonatedClients(cfg *rest.Config, user *User) (*impersonatedClients, error) {
+	impersonated := rest.CopyConfig(cfg)
+	impersonated.Impersonate = rest.ImpersonationConfig{
+		UserName: user.Name,
+		UID:      user.UID,
+		Groups:   user.Groups,
+		Extra:    user.Extra,
+	}
+	return newClients(impersonated)
+}
+
+func newClients(cfg *rest.Config) (*impersonatedClients, error) {
+	kube, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := versioned.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &impersonatedClients{kube: kube, pipeline: pipeline, dynamic: dynamicClient}, nil
+}
+
+// authorize checks that the authenticated caller, if any, is allowed to perform the verb on the
+// resource. Reads served from the informer caches go through it, as they don't hit the API server
+// with the caller's identity.
+func authorize(ctx context.Context, verb, group, resource, subresource, namespace, name string) error {
+	if err, ok := ctx.Value(impersonationErrorKey{}).(error); ok {
+		return err
+	}
+	user := UserFromContext(ctx)
+	if user == nil {
+		return nil
+	}
+
+	review, err := kubeclient.Get(ctx).AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        verb,
+				Group:       group,
+				Resource:    resource,
+				Subresource: subresource,
+				Name:        name,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to check the permissions of %s: %w", user.Name, err)
+	}
+	if !review.Status.Allowed {
+		target := resource
+		if subresource != "" {
+			target = fmt.Sprintf("%s/%s", resource, subresource)
+		}
+		scope := "cluster-wide"
+		if namespace != "" {
+			scope = fmt.Sprintf("in namespace %s", namespace)
+		}
+		return fmt.Errorf("user %s is not allowed to %s %s %s", user.Name, verb, target, scope)
+	}
+	return nil
+}
+
+// access is a permission required to call a tool.
+type access struct {
+	verb        string
+	group       string
+	resource    string
+	subresource string
+}
+
+// tektonAccess returns the access to a tekton.dev resource.
+func tektonAccess(verb, resource string) access {
+	return access{verb: verb, group: "tekton.dev", resource: resource}
+}
+
+// kindAccess returns the access to the resource named by the "kind" argument of a tool.
+func kindAccess(verb string) func(mcp.CallToolRequest) []access {
+	return func(request mcp.CallToolRequest) []access {
+		kind, _ := request.Params.Arguments["kind"].(string)
+		return []access{tektonAccess(verb, fmt.Sprintf("%ss", kind))}
+	}
+}
+
+func staticAccess(accesses ...access) func(mcp.CallToolRequest) []access {
+	return func(mcp.CallToolRequest) []access {
+		return accesses
+	}
+}
+
+// toolAccess lists the permissions needed to call each tool, on the namespace given in its arguments.
+// Writes also go through the impersonating clients, these checks cover the reads from the informer caches.
+// The list tools are added along with the listKinds.
+var toolAccess = map[string]func(mcp.CallToolRequest) []access{
+	"start_pipeline":     staticAccess(tektonAccess("get", "pipelines")),
+	"start_task":         staticAccess(tektonAccess("get", "tasks")),
+	"start_customrun":    staticAccess(tektonAccess("create", "customruns")),
+	"cancel_pipelinerun": staticAccess(tektonAccess("get", "pipelineruns")),
+	"cancel_taskrun":     staticAccess(tektonAccess("get", "taskruns")),
+	"rerun":              kindAccess("get"),
+	"inspect_provenance": kindAccess("get"),
+	"simulate_trigger":   staticAccess(access{verb: "get", group: triggersGroup, resource: "eventlisteners"}),
+	"get_run_trigger": func(request mcp.CallToolRequest) []access {
+		if _, ok := request.Params.Arguments["kind"]; !ok {
+			return []access{tektonAccess("get", "pipelineruns")}
+		}
+		return kindAccess("get")(request)
+	},
+	"get_logs": func(request mcp.CallToolRequest) []access {
+		return append(kindAccess("get")(request), access{verb: "get", resource: "pods", subresource: "log"})
+	},
+}
+
+// AuthorizeToolCall is a tool middleware checking that the authenticated caller, if any, has the
+// permissions needed by the tool. Tools without known permissions are denied to authenticated callers.
+func AuthorizeToolCall(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err, ok := ctx.Value(impersonationErrorKey{}).(error); ok {
+			return mcpError(err.Error()), nil
+		}
+		if UserFromContext(ctx) == nil {
+			return next(ctx, request)
+		}
+
+		accessFunc, ok := toolAccess[request.Params.Name]
+		if !ok {
+			return mcpError(fmt.Sprintf("Tool %s is not available to authenticated users", request.Params.Name)), nil
+		}
+		namespace, _ := request.Params.Arguments["namespace"].(string)
+		if namespace == "" && !strings.HasPrefix(request.Params.Name, "list_") {
+			// The tool acts on the default namespace, list tools on all namespaces
+			namespace, _ = ConfigFromContext(ctx).Namespace("")
+		}
+		for _, a := range accessFunc(request) {
+			if err := authorize(ctx, a.verb, a.group, a.resource, a.subresource, namespace, ""); err != nil {
+				return mcpError(err.Error()), nil
+			}
+		}
+		return next(ctx, request)
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClientsCache(t *testing.T) {
+	c := newClientsCache(time.Minute)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := 0
+	create := func() (*impersonatedClients, error) {
+		created++
+		return &impersonatedClients{}, nil
+	}
+
+	alice, err := c.get("alice", start, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Used within the TTL, the clients are reused and kept
+	for _, after := range []time.Duration{30 * time.Second, 80 * time.Second} {
+		got, err := c.get("alice", start.Add(after), create)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != alice || created != 1 {
+			t.Errorf("after %s: got new clients, %d created", after, created)
+		}
+	}
+	if _, err := c.get("bob", start.Add(90*time.Second), create); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.entries) != 2 || created != 2 {
+		t.Errorf("got %d entries and %d created, want 2 and 2", len(c.entries), created)
+	}
+
+	// A call after the TTL drops the clients of the callers that were idle
+	if _, err := c.get("carol", start.Add(150*time.Second+time.Minute), create); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.entries["alice"]; ok {
+		t.Error("the clients of alice were kept")
+	}
+	if _, ok := c.entries["bob"]; ok {
+		t.Error("the clients of bob were kept")
+	}
+	got, err := c.get("alice", start.Add(150*time.Second+time.Minute), create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == alice {
+		t.Error("got the expired clients of alice")
+	}
+
+	// Failures are not cached
+	failed := errors.New("invalid config")
+	if _, err := c.get("dave", start, func() (*impersonatedClients, error) { return nil, failed }); !errors.Is(err, failed) {
+		t.Errorf("got error %v, want %v", err, failed)
+	}
+	if _, ok := c.entries["dave"]; ok {
+		t.Error("the failure for dave was cached")
+	}
+}
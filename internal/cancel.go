@@ -0,0 +1,295 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
+	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
+	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+// defaultAcknowledgeTimeout is how long the cancel tools wait for the controller to acknowledge a cancellation.
+const defaultAcknowledgeTimeout = 30 * time.Second
+
+// pipelineRunCancelReasons maps each spec.status a PipelineRun can be cancelled with to the
+// condition reasons showing that the controller acknowledged it.
+var pipelineRunCancelReasons = map[string][]string{
+	v1.PipelineRunSpecStatusCancelled: {
+		v1.PipelineRunReasonCancelled.String(),
+	},
+	v1.PipelineRunSpecStatusCancelledRunFinally: {
+		v1.PipelineRunReasonCancelledRunningFinally.String(),
+		v1.PipelineRunReasonCancelled.String(),
+	},
+	v1.PipelineRunSpecStatusStoppedRunFinally: {
+		v1.PipelineRunReasonStopping.String(),
+		v1.PipelineRunReasonStoppedRunningFinally.String(),
+		v1.PipelineRunReasonCancelled.String(),
+	},
+}
+
+func toolCancelPipelineRun() mcp.Tool {
+	return mcp.NewTool("cancel_pipelinerun",
+		mcp.WithDescription("Cancel or gracefully stop a running PipelineRun"),
+		mcp.WithString("name", mcp.Required(),
+			mcp.Description("Name of the PipelineRun to cancel"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace where the PipelineRun is located, defaults to the server's default namespace"),
+		),
+		mcp.WithString("mode",
+			mcp.Description("Cancelled stops everything now, CancelledRunFinally cancels running tasks and then runs the finally tasks, StoppedRunFinally lets running tasks complete, skips the others and runs the finally tasks"),
+			mcp.Enum(v1.PipelineRunSpecStatusCancelled, v1.PipelineRunSpecStatusCancelledRunFinally, v1.PipelineRunSpecStatusStoppedRunFinally),
+			mcp.DefaultString(v1.PipelineRunSpecStatusCancelled),
+		),
+		mcp.WithBoolean("dry-run",
+			mcp.Description("Only show which child TaskRuns would be affected, without cancelling anything"),
+		),
+		mcp.WithString("wait-timeout",
+			mcp.Description("How long to wait for the controller to acknowledge the cancellation, as a duration (e.g. 1m), defaults to 30s"),
+		),
+	)
+}
+
+func toolCancelTaskRun() mcp.Tool {
+	return mcp.NewTool("cancel_taskrun",
+		mcp.WithDescription("Cancel a running TaskRun"),
+		mcp.WithString("name", mcp.Required(),
+			mcp.Description("Name of the TaskRun to cancel"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace where the TaskRun is located, defaults to the server's default namespace"),
+		),
+		mcp.WithBoolean("dry-run",
+			mcp.Description("Only show the steps that would be affected, without cancelling anything"),
+		),
+		mcp.WithString("wait-timeout",
+			mcp.Description("How long to wait for the controller to acknowledge the cancellation, as a duration (e.g. 1m), defaults to 30s"),
+		),
+	)
+}
+
+// affectedChild describes what cancelling a PipelineRun would do to one of its child TaskRuns or
+// CustomRuns.
+type affectedChild struct {
+	childOutcome
+	Effect string `json:"effect"`
+}
+
+func handlerCancelPipelineRun(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, namespace, dryRun, timeout, err := cancelParams(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	mode, err := OptionalParam[string](request, "mode")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if mode == "" {
+		mode = v1.PipelineRunSpecStatusCancelled
+	}
+	acknowledged, ok := pipelineRunCancelReasons[mode]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("mode %s is not one of %s, %s or %s", mode, v1.PipelineRunSpecStatusCancelled, v1.PipelineRunSpecStatusCancelledRunFinally, v1.PipelineRunSpecStatusStoppedRunFinally)), nil
+	}
+
+	pipelineRunInformer := pipelineruninformer.Get(ctx)
+	pr, err := pipelineRunInformer.Lister().PipelineRuns(namespace).Get(name)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to get PipelineRun %s/%s: %v", namespace, name, err)), nil
+	}
+	if pr.IsDone() {
+		return mcpError(fmt.Sprintf("PipelineRun %s/%s is already done", namespace, name)), nil
+	}
+
+	if dryRun {
+		affected, err := affectedChildren(ctx, pr, mode)
+		if err != nil {
+			return mcpError(fmt.Sprintf("Failed to list TaskRuns of PipelineRun %s/%s: %v", namespace, name, err)), nil
+		}
+		return runResult("pipelinerun", pr, fmt.Sprintf("Dry run: PipelineRun would be patched with spec.status=%s", mode), affected)
+	}
+
+	pipelineclientset := pipelineclient.Get(ctx)
+	patch, err := json.Marshal(map[string]any{"spec": map[string]any{"status": mode}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patch to JSON: %w", err)
+	}
+	if _, err := pipelineclientset.TektonV1().PipelineRuns(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return mcpError(fmt.Sprintf("Failed to patch PipelineRun %s/%s: %v", namespace, name, err)), nil
+	}
+
+	watcher := newRunWatcher()
+	defer watcher.stop()
+	if err := watcher.watch(pipelineRunInformer.Informer(), func(o metav1.Object) bool {
+		return o.GetNamespace() == namespace && o.GetName() == name
+	}); err != nil {
+		return nil, err
+	}
+	done := watcher.wait(ctx, timeout, func() bool {
+		current, err := pipelineRunInformer.Lister().PipelineRuns(namespace).Get(name)
+		if err != nil {
+			return false
+		}
+		pr = current
+		_, reason, _ := succeededCondition(pr.Status.Status)
+		return pr.IsDone() || slices.Contains(acknowledged, reason)
+	})
+
+	outcome := pipelineRunOutcome(ctx, pr)
+	summary := fmt.Sprintf("PipelineRun patched with spec.status=%s, now %s", mode, childState(outcome.Status, outcome.Reason))
+	if !done {
+		summary = fmt.Sprintf("PipelineRun patched with spec.status=%s, the controller didn't acknowledge it before the wait timeout", mode)
+	}
+	return runResult("pipelinerun", pr, summary, outcome)
+}
+
+func handlerCancelTaskRun(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, namespace, dryRun, timeout, err := cancelParams(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	taskRunInformer := taskruninformer.Get(ctx)
+	tr, err := taskRunInformer.Lister().TaskRuns(namespace).Get(name)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to get TaskRun %s/%s: %v", namespace, name, err)), nil
+	}
+	if tr.IsDone() {
+		return mcpError(fmt.Sprintf("TaskRun %s/%s is already done", namespace, name)), nil
+	}
+
+	if dryRun {
+		return runResult("taskrun", tr, fmt.Sprintf("Dry run: TaskRun would be patched with spec.status=%s", v1.TaskRunSpecStatusCancelled), taskRunOutcome(tr).Steps)
+	}
+
+	pipelineclientset := pipelineclient.Get(ctx)
+	patch, err := json.Marshal(map[string]any{"spec": map[string]any{"status": v1.TaskRunSpecStatusCancelled}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patch to JSON: %w", err)
+	}
+	if _, err := pipelineclientset.TektonV1().TaskRuns(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return mcpError(fmt.Sprintf("Failed to patch TaskRun %s/%s: %v", namespace, name, err)), nil
+	}
+
+	watcher := newRunWatcher()
+	defer watcher.stop()
+	if err := watcher.watch(taskRunInformer.Informer(), func(o metav1.Object) bool {
+		return o.GetNamespace() == namespace && o.GetName() == name
+	}); err != nil {
+		return nil, err
+	}
+	done := watcher.wait(ctx, timeout, func() bool {
+		current, err := taskRunInformer.Lister().TaskRuns(namespace).Get(name)
+		if err != nil {
+			return false
+		}
+		tr = current
+		return tr.IsDone()
+	})
+
+	outcome := taskRunOutcome(tr)
+	summary := fmt.Sprintf("TaskRun patched with spec.status=%s, now %s", v1.TaskRunSpecStatusCancelled, childState(outcome.Status, outcome.Reason))
+	if !done {
+		summary = fmt.Sprintf("TaskRun patched with spec.status=%s, the controller didn't acknowledge it before the wait timeout", v1.TaskRunSpecStatusCancelled)
+	}
+	return runResult("taskrun", tr, summary, outcome)
+}
+
+// cancelParams reads the parameters shared by the cancel tools.
+func cancelParams(ctx context.Context, request mcp.CallToolRequest) (string, string, bool, time.Duration, error) {
+	name, err := OptionalParam[string](request, "name")
+	if err != nil {
+		return "", "", false, 0, err
+	}
+	if name == "" {
+		return "", "", false, 0, fmt.Errorf("name is required")
+	}
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return "", "", false, 0, err
+	}
+	dryRun, err := OptionalParam[bool](request, "dry-run")
+	if err != nil {
+		return "", "", false, 0, err
+	}
+	timeout := defaultAcknowledgeTimeout
+	t, err := OptionalParam[string](request, "wait-timeout")
+	if err != nil {
+		return "", "", false, 0, err
+	}
+	if t != "" {
+		timeout, err = time.ParseDuration(t)
+		if err != nil {
+			return "", "", false, 0, fmt.Errorf("parameter wait-timeout is not a valid duration: %w", err)
+		}
+	}
+	return name, namespace, dryRun, timeout, nil
+}
+
+// affectedChildren lists the child TaskRuns and CustomRuns of a PipelineRun and what cancelling it
+// with the given mode would do to each of them.
+func affectedChildren(ctx context.Context, pr *v1.PipelineRun, mode string) ([]affectedChild, error) {
+	selector := labels.SelectorFromSet(labels.Set{pipeline.PipelineRunLabelKey: pr.Name})
+	trs, err := taskruninformer.Get(ctx).Lister().TaskRuns(pr.Namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	crs, err := customruninformer.Get(ctx).Lister().CustomRuns(pr.Namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var affected []affectedChild
+	addChild := func(kind string, run metav1.Object, status duckv1.Status, start, completion *metav1.Time, done bool) {
+		condition, reason, _ := succeededCondition(status)
+		child := affectedChild{
+			childOutcome: childOutcome{
+				Kind:         kind,
+				Name:         run.GetName(),
+				PipelineTask: run.GetLabels()[pipeline.PipelineTaskLabelKey],
+				Status:       condition,
+				Reason:       reason,
+				Duration:     runDuration(start, completion),
+			},
+		}
+		switch {
+		case done:
+			child.Effect = "none, already done"
+		case mode == v1.PipelineRunSpecStatusStoppedRunFinally:
+			child.Effect = "keeps running until it completes"
+		default:
+			child.Effect = "cancelled"
+		}
+		affected = append(affected, child)
+	}
+	for _, tr := range trs {
+		addChild("TaskRun", tr, tr.Status.Status, tr.Status.StartTime, tr.Status.CompletionTime, tr.IsDone())
+	}
+	for _, cr := range crs {
+		addChild("CustomRun", cr, cr.Status.Status, cr.Status.StartTime, cr.Status.CompletionTime, cr.IsDone())
+	}
+
+	if mode != v1.PipelineRunSpecStatusCancelled && pr.Status.PipelineSpec != nil {
+		for _, task := range pr.Status.PipelineSpec.Finally {
+			affected = append(affected, affectedChild{
+				childOutcome: childOutcome{PipelineTask: task.Name},
+				Effect:       "finally task, runs after the other tasks are stopped",
+			})
+		}
+	}
+
+	return affected, nil
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// celEvent is the event evaluated by the CEL expressions of the cel interceptor.
+type celEvent struct {
+	body       any
+	header     http.Header
+	extensions map[string]any
+	requestURL string
+}
+
+// newCELEnv returns the CEL environment of the cel interceptor of Tekton Triggers, with the functions
+// that don't need access to the cluster (compareSecret is not available).
+func newCELEnv() (*cel.Env, error) {
+	headerType := cel.MapType(cel.StringType, cel.ListType(cel.StringType))
+	return cel.NewEnv(
+		ext.Strings(),
+		ext.Encoders(),
+		cel.Variable("body", cel.DynType),
+		cel.Variable("header", headerType),
+		cel.Variable("extensions", cel.DynType),
+		cel.Variable("requestURL", cel.StringType),
+		cel.Function("match",
+			cel.MemberOverload("match_header_string_string", []*cel.Type{headerType, cel.StringType, cel.StringType}, cel.BoolType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					h, err := celHeader(args[0])
+					if err != nil {
+						return types.NewErr("%s", err)
+					}
+					return types.Bool(h.Get(fmt.Sprint(args[1].Value())) == fmt.Sprint(args[2].Value()))
+				}),
+			),
+		),
+		cel.Function("canonical",
+			cel.MemberOverload("canonical_header_string", []*cel.Type{headerType, cel.StringType}, cel.StringType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					h, err := celHeader(lhs)
+					if err != nil {
+						return types.NewErr("%s", err)
+					}
+					return types.String(h.Get(fmt.Sprint(rhs.Value())))
+				}),
+			),
+		),
+		cel.Function("truncate",
+			cel.MemberOverload("truncate_string_int", []*cel.Type{cel.StringType, cel.IntType}, cel.StringType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					s, n := []rune(fmt.Sprint(lhs.Value())), int(rhs.(types.Int))
+					if n < 0 || n >= len(s) {
+						return lhs
+					}
+					return types.String(s[:n])
+				}),
+			),
+		),
+		cel.Function("parseJSON",
+			cel.MemberOverload("parseJSON_string", []*cel.Type{cel.StringType}, cel.DynType,
+				cel.UnaryBinding(func(value ref.Val) ref.Val {
+					var parsed any
+					if err := json.Unmarshal([]byte(fmt.Sprint(value.Value())), &parsed); err != nil {
+						return types.NewErr("failed to parse JSON: %s", err)
+					}
+					return types.DefaultTypeAdapter.NativeToValue(parsed)
+				}),
+			),
+		),
+		cel.Function("marshalJSON",
+			cel.MemberOverload("marshalJSON_dyn", []*cel.Type{cel.DynType}, cel.StringType,
+				cel.UnaryBinding(func(value ref.Val) ref.Val {
+					native, err := celNative(value)
+					if err != nil {
+						return types.NewErr("%s", err)
+					}
+					data, err := json.Marshal(native)
+					if err != nil {
+						return types.NewErr("failed to marshal JSON: %s", err)
+					}
+					return types.String(data)
+				}),
+			),
+		),
+	)
+}
+
+func celHeader(value ref.Val) (http.Header, error) {
+	h, err := value.ConvertToNative(reflect.TypeOf(map[string][]string{}))
+	if err != nil {
+		return nil, err
+	}
+	return http.Header(h.(map[string][]string)), nil
+}
+
+// celNative converts a CEL value to the JSON value it represents.
+func celNative(value ref.Val) (any, error) {
+	v, err := value.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the CEL value %v: %w", value, err)
+	}
+	data, err := protojson.Marshal(v.(*structpb.Value))
+	if err != nil {
+		return nil, err
+	}
+	var native any
+	if err := json.Unmarshal(data, &native); err != nil {
+		return nil, err
+	}
+	return native, nil
+}
+
+// evalCEL evaluates an expression of the cel interceptor on the event.
+func evalCEL(env *cel.Env, expression string, event celEvent) (ref.Val, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid CEL expression %q: %w", expression, issues.Err())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CEL expression %q: %w", expression, err)
+	}
+	header := map[string][]string{}
+	for k, v := range event.header {
+		header[http.CanonicalHeaderKey(k)] = v
+	}
+	extensions := event.extensions
+	if extensions == nil {
+		extensions = map[string]any{}
+	}
+	value, _, err := program.Eval(map[string]any{
+		"body":       event.body,
+		"header":     header,
+		"extensions": extensions,
+		"requestURL": event.requestURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate CEL expression %q: %w", expression, err)
+	}
+	return value, nil
+}
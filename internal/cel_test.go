@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestEvalCEL(t *testing.T) {
+	env, err := newCELEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := celEvent{
+		body: map[string]any{
+			"ref":     "refs/heads/main",
+			"payload": `{"action":"opened","number":42}`,
+			"labels":  []any{"bug", "ci"},
+		},
+		header:     http.Header{"x-github-event": {"pull_request"}},
+		extensions: map[string]any{"team": "a"},
+		requestURL: "http://el-listener.default.svc:8080/hooks",
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		want       any
+		wantErr    bool
+	}{
+		{name: "body", expression: "body.ref", want: "refs/heads/main"},
+		{name: "header is canonicalized", expression: "header['X-Github-Event'][0]", want: "pull_request"},
+		{name: "match", expression: "header.match('X-GitHub-Event', 'pull_request')", want: true},
+		{name: "match fails", expression: "header.match('X-GitHub-Event', 'push')", want: false},
+		{name: "canonical", expression: "header.canonical('x-github-event')", want: "pull_request"},
+		{name: "extensions", expression: "extensions.team", want: "a"},
+		{name: "requestURL", expression: "requestURL.endsWith('/hooks')", want: true},
+		{name: "truncate", expression: "body.ref.truncate(4)", want: "refs"},
+		{name: "truncate longer than the string", expression: "'abc'.truncate(10)", want: "abc"},
+		{name: "parseJSON", expression: "body.payload.parseJSON().number", want: float64(42)},
+		{name: "marshalJSON", expression: "body.labels.marshalJSON()", want: `["bug","ci"]`},
+		{name: "strings extension", expression: "body.ref.split('/')[2]", want: "main"},
+		{name: "encoders extension", expression: "base64.encode(b'ci')", want: "Y2k="},
+		{name: "missing field", expression: "body.missing", wantErr: true},
+		{name: "invalid JSON", expression: "body.ref.parseJSON()", wantErr: true},
+		{name: "compareSecret is not available", expression: "header.canonical('X-Hub-Signature').compareSecret('token', 'secret')", wantErr: true},
+		{name: "syntax error", expression: "body.ref ==", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := evalCEL(env, test.expression, event)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("evalCEL() error = %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			got, err := celNative(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
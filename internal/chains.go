@@ -0,0 +1,468 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations set by Tekton Chains on the runs it signs. The payload, signature, certificate and
+// chain annotations are suffixed with the kind and UID of the run, e.g. payload-taskrun-<uid>.
+const (
+	ChainsSignedAnnotationKey       = "chains.tekton.dev/signed"
+	ChainsTransparencyAnnotationKey = "chains.tekton.dev/transparency"
+	chainsAnnotationPrefix          = "chains.tekton.dev/"
+)
+
+func toolInspectProvenance() mcp.Tool {
+	return mcp.NewTool("inspect_provenance",
+		mcp.WithDescription("Inspect the provenance Tekton Chains stored on a PipelineRun or TaskRun: decode its in-toto/SLSA payload, "+
+			"verify its signature against a public key, and summarize its builder, materials and subjects"),
+		mcp.WithString("kind", mcp.Required(),
+			mcp.Description("Kind of the run"),
+			mcp.Enum("pipelinerun", "taskrun"),
+		),
+		mcp.WithString("name", mcp.Required(),
+			mcp.Description("Name of the run"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace where the run is located, defaults to the server's default namespace"),
+		),
+		mcp.WithString("public-key",
+			mcp.Description("PEM encoded public key (or certificate) of the Chains signing key, e.g. the content of cosign.pub, to verify the signature"),
+		),
+	)
+}
+
+// provenanceSummary describes the provenance of a run signed by Tekton Chains.
+type provenanceSummary struct {
+	Run string `json:"run"`
+	// Signed is the value of the chains.tekton.dev/signed annotation: true, or failed.
+	Signed       string `json:"signed,omitempty"`
+	Transparency string `json:"transparency,omitempty"`
+	PayloadType  string `json:"payloadType,omitempty"`
+	// Statement is the in-toto statement type, empty if the payload is not an in-toto statement.
+	Statement     string               `json:"statement,omitempty"`
+	PredicateType string               `json:"predicateType,omitempty"`
+	Builder       string               `json:"builder,omitempty"`
+	BuildType     string               `json:"buildType,omitempty"`
+	StartedOn     string               `json:"startedOn,omitempty"`
+	FinishedOn    string               `json:"finishedOn,omitempty"`
+	Subjects      []provenanceArtifact `json:"subjects,omitempty"`
+	Materials     []provenanceArtifact `json:"materials,omitempty"`
+	Signature     signatureCheck       `json:"signature"`
+	Notes         []string             `json:"notes,omitempty"`
+}
+
+// provenanceArtifact is a subject or a material of a provenance, named by its name or URI.
+type provenanceArtifact struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// signatureCheck is the outcome of the verification of the signature of a provenance.
+type signatureCheck struct {
+	// Result is one of verified, invalid, unverified (no public key was given) or missing.
+	Result string `json:"result"`
+	KeyID  string `json:"keyID,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// dsseEnvelope is the DSSE envelope Chains stores as the signature of the in-toto payloads.
+type dsseEnvelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		KeyID string `json:"keyid"`
+		Sig   string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// inTotoStatement is an in-toto statement, with a SLSA v0.2 or v1 provenance predicate.
+type inTotoStatement struct {
+	Type          string `json:"_type"`
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	Predicate json.RawMessage `json:"predicate"`
+}
+
+// slsaMaterial is a material of a SLSA v0.2 provenance, or a resolved dependency of a v1 one.
+type slsaMaterial struct {
+	URI    string            `json:"uri"`
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type slsaV02Predicate struct {
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	BuildType string         `json:"buildType"`
+	Materials []slsaMaterial `json:"materials"`
+	Metadata  struct {
+		BuildStartedOn  string `json:"buildStartedOn"`
+		BuildFinishedOn string `json:"buildFinishedOn"`
+	} `json:"metadata"`
+}
+
+type slsaV1Predicate struct {
+	BuildDefinition struct {
+		BuildType            string         `json:"buildType"`
+		ResolvedDependencies []slsaMaterial `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Metadata struct {
+			StartedOn  string `json:"startedOn"`
+			FinishedOn string `json:"finishedOn"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+func handlerInspectProvenance(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	kind, err := OptionalParam[string](request, "kind")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	k, ok := listKindOf(kind)
+	if !ok || resultsRecordTypes[kind] == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("kind %q is not one of pipelinerun or taskrun", kind)), nil
+	}
+	name, err := OptionalParam[string](request, "name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var publicKey crypto.PublicKey
+	if pemKey, err := OptionalParam[string](request, "public-key"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	} else if pemKey != "" {
+		if publicKey, err = parsePublicKey(pemKey); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("parameter public-key is invalid: %v", err)), nil
+		}
+	}
+
+	var run metav1.Object
+	obj, err := k.getCached(ctx, namespace, name)
+	if err == nil {
+		run, _ = obj.(metav1.Object)
+	} else if apierrors.IsNotFound(err) {
+		// The annotations are archived along with the run by Tekton Results
+		archived, _, resultsErr := getArchived(ctx, k, namespace, name)
+		if resultsErr != nil {
+			err = fmt.Errorf("%w, and failed to read Tekton Results: %w", err, resultsErr)
+		}
+		if archived != nil {
+			run, err = archived, nil
+		}
+	}
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to get %s %s/%s: %v", k.kind, namespace, name, err)), nil
+	}
+
+	summary, err := inspectProvenance(k, run, publicKey)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to inspect the provenance of %s %s/%s: %v", k.kind, namespace, name, err)), nil
+	}
+	jsonData, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal details to JSON: %w", err)
+	}
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.NewTextContent(provenanceText(k, run, summary)),
+		mcp.NewTextContent(string(jsonData)),
+	}}, nil
+}
+
+// inspectProvenance decodes the provenance stored by Chains in the annotations of a run, and
+// verifies its signature if a public key is given.
+func inspectProvenance(k listKind, run metav1.Object, publicKey crypto.PublicKey) (provenanceSummary, error) {
+	annotations := run.GetAnnotations()
+	summary := provenanceSummary{
+		Run:          TektonResourceURI(k.resourceType, run.GetNamespace(), run.GetName()),
+		Signed:       annotations[ChainsSignedAnnotationKey],
+		Transparency: annotations[ChainsTransparencyAnnotationKey],
+		Signature:    signatureCheck{Result: "missing"},
+	}
+	key := fmt.Sprintf("%s-%s", k.resourceType, run.GetUID())
+	payloadAnnotation := annotations[fmt.Sprintf("%spayload-%s", chainsAnnotationPrefix, key)]
+	signatureAnnotation := annotations[fmt.Sprintf("%ssignature-%s", chainsAnnotationPrefix, key)]
+
+	switch {
+	case summary.Signed == "" && payloadAnnotation == "":
+		return summary, fmt.Errorf("the run has no %s annotation, Tekton Chains did not process it", ChainsSignedAnnotationKey)
+	case summary.Signed != "true":
+		summary.Notes = append(summary.Notes, fmt.Sprintf("Tekton Chains did not sign the run, %s is %q", ChainsSignedAnnotationKey, summary.Signed))
+	}
+	if annotations[fmt.Sprintf("%scert-%s", chainsAnnotationPrefix, key)] != "" {
+		summary.Notes = append(summary.Notes, "the run was signed with a keyless certificate, verify it with cosign against the certificate and the transparency log")
+	}
+
+	var payload []byte
+	if payloadAnnotation != "" {
+		decoded, err := base64.StdEncoding.DecodeString(payloadAnnotation)
+		if err != nil {
+			return summary, fmt.Errorf("the payload annotation is not valid base64: %w", err)
+		}
+		payload = decoded
+	}
+
+	var signature []byte
+	if signatureAnnotation != "" {
+		decoded, err := base64.StdEncoding.DecodeString(signatureAnnotation)
+		if err != nil {
+			return summary, fmt.Errorf("the signature annotation is not valid base64: %w", err)
+		}
+		signature = decoded
+	}
+
+	// The in-toto payloads are signed in a DSSE envelope, the other formats are signed as is
+	var envelope dsseEnvelope
+	if json.Unmarshal(signature, &envelope) == nil && envelope.PayloadType != "" {
+		summary.PayloadType = envelope.PayloadType
+		enveloped, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			return summary, fmt.Errorf("the payload of the DSSE envelope is not valid base64: %w", err)
+		}
+		if payload != nil && string(payload) != string(enveloped) {
+			summary.Notes = append(summary.Notes, "the payload annotation differs from the payload of the signed DSSE envelope, the signed payload is summarized")
+		}
+		payload = enveloped
+		summary.Signature = verifyEnvelope(envelope, payload, publicKey)
+	} else if signature != nil {
+		if payload == nil {
+			return summary, errors.New("the run has a signature but no payload annotation")
+		}
+		summary.Signature = verifyPayload(payload, signature, publicKey)
+	}
+
+	if payload == nil {
+		summary.Notes = append(summary.Notes, "the provenance is not stored on the run, Chains may store it in an OCI registry or another storage backend")
+		return summary, nil
+	}
+	summarizeStatement(&summary, payload)
+	return summary, nil
+}
+
+// summarizeStatement fills the summary with the builder, subjects and materials of an in-toto payload.
+func summarizeStatement(summary *provenanceSummary, payload []byte) {
+	var statement inTotoStatement
+	if err := json.Unmarshal(payload, &statement); err != nil || statement.Type == "" {
+		summary.Notes = append(summary.Notes, "the payload is not an in-toto statement (e.g. the tekton format), it has no provenance to summarize")
+		return
+	}
+	summary.Statement = statement.Type
+	summary.PredicateType = statement.PredicateType
+	for _, s := range statement.Subject {
+		summary.Subjects = append(summary.Subjects, provenanceArtifact{Name: s.Name, Digest: s.Digest})
+	}
+
+	var materials []slsaMaterial
+	switch statement.PredicateType {
+	case "https://slsa.dev/provenance/v0.2":
+		var p slsaV02Predicate
+		if err := json.Unmarshal(statement.Predicate, &p); err != nil {
+			summary.Notes = append(summary.Notes, fmt.Sprintf("failed to decode the SLSA v0.2 predicate: %v", err))
+			return
+		}
+		summary.Builder = p.Builder.ID
+		summary.BuildType = p.BuildType
+		summary.StartedOn = p.Metadata.BuildStartedOn
+		summary.FinishedOn = p.Metadata.BuildFinishedOn
+		materials = p.Materials
+	case "https://slsa.dev/provenance/v1":
+		var p slsaV1Predicate
+		if err := json.Unmarshal(statement.Predicate, &p); err != nil {
+			summary.Notes = append(summary.Notes, fmt.Sprintf("failed to decode the SLSA v1 predicate: %v", err))
+			return
+		}
+		summary.Builder = p.RunDetails.Builder.ID
+		summary.BuildType = p.BuildDefinition.BuildType
+		summary.StartedOn = p.RunDetails.Metadata.StartedOn
+		summary.FinishedOn = p.RunDetails.Metadata.FinishedOn
+		materials = p.BuildDefinition.ResolvedDependencies
+	default:
+		summary.Notes = append(summary.Notes, fmt.Sprintf("predicate type %s is not a SLSA provenance, only its subjects are summarized", statement.PredicateType))
+	}
+	for _, m := range materials {
+		name := m.URI
+		if name == "" {
+			name = m.Name
+		}
+		summary.Materials = append(summary.Materials, provenanceArtifact{Name: name, Digest: m.Digest})
+	}
+}
+
+// verifyEnvelope verifies the signatures of a DSSE envelope, over its pre-authentication encoding.
+// The envelope is verified if any of its signatures is.
+func verifyEnvelope(envelope dsseEnvelope, payload []byte, publicKey crypto.PublicKey) signatureCheck {
+	if len(envelope.Signatures) == 0 {
+		return signatureCheck{Result: "missing"}
+	}
+	if publicKey == nil {
+		return signatureCheck{Result: "unverified", KeyID: envelope.Signatures[0].KeyID}
+	}
+	message := fmt.Sprintf("DSSEv1 %d %s %d %s", len(envelope.PayloadType), envelope.PayloadType, len(payload), payload)
+	var errs []error
+	for _, s := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err == nil {
+			err = verifySignature(publicKey, []byte(message), sig)
+		}
+		if err == nil {
+			return signatureCheck{Result: "verified", KeyID: s.KeyID}
+		}
+		errs = append(errs, err)
+	}
+	return signatureCheck{Result: "invalid", KeyID: envelope.Signatures[0].KeyID, Error: errors.Join(errs...).Error()}
+}
+
+// verifyPayload verifies a signature made over the payload itself.
+func verifyPayload(payload, signature []byte, publicKey crypto.PublicKey) signatureCheck {
+	if publicKey == nil {
+		return signatureCheck{Result: "unverified"}
+	}
+	if err := verifySignature(publicKey, payload, signature); err != nil {
+		return signatureCheck{Result: "invalid", Error: err.Error()}
+	}
+	return signatureCheck{Result: "verified"}
+}
+
+// verifySignature verifies a signature made like the signers of Chains (sigstore) make them: over the
+// SHA-256 digest of the message for ECDSA and RSA keys, and over the message itself for ed25519 keys.
+func verifySignature(publicKey crypto.PublicKey, message, sig []byte) error {
+	digest := sha256.Sum256(message)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errors.New("ECDSA signature does not match the public key")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, sig) {
+			return errors.New("ed25519 signature does not match the public key")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			if rsa.VerifyPSS(key, crypto.SHA256, digest[:], sig, nil) != nil {
+				return fmt.Errorf("RSA signature does not match the public key: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+	return nil
+}
+
+// parsePublicKey parses a PEM encoded public key, or the public key of a PEM encoded certificate.
+func parsePublicKey(pemKey string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(pemKey)))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("PEM block %s is not a public key or a certificate", block.Type)
+}
+
+// provenanceText renders the summary for a reviewer.
+func provenanceText(k listKind, run metav1.Object, s provenanceSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s/%s\n", k.kind, run.GetNamespace(), run.GetName())
+	switch s.Signed {
+	case "true":
+		b.WriteString("Signed by Tekton Chains: yes\n")
+	case "":
+		b.WriteString("Signed by Tekton Chains: no\n")
+	default:
+		fmt.Fprintf(&b, "Signed by Tekton Chains: no (%s)\n", s.Signed)
+	}
+	switch s.Signature.Result {
+	case "verified":
+		b.WriteString("Signature: VERIFIED with the supplied public key\n")
+	case "invalid":
+		fmt.Fprintf(&b, "Signature: INVALID for the supplied public key: %s\n", s.Signature.Error)
+	case "unverified":
+		b.WriteString("Signature: present, not verified (no public key supplied)\n")
+	default:
+		b.WriteString("Signature: missing\n")
+	}
+	if s.Transparency != "" {
+		fmt.Fprintf(&b, "Transparency log entry: %s\n", s.Transparency)
+	}
+	if s.PredicateType != "" {
+		fmt.Fprintf(&b, "Provenance: %s\n", s.PredicateType)
+	}
+	if s.Builder != "" {
+		fmt.Fprintf(&b, "Builder: %s\n", s.Builder)
+	}
+	if s.BuildType != "" {
+		fmt.Fprintf(&b, "Build type: %s\n", s.BuildType)
+	}
+	if s.StartedOn != "" || s.FinishedOn != "" {
+		fmt.Fprintf(&b, "Built from %s to %s\n", s.StartedOn, s.FinishedOn)
+	}
+	for _, section := range []struct {
+		title     string
+		artifacts []provenanceArtifact
+	}{{"Subjects", s.Subjects}, {"Materials", s.Materials}} {
+		if len(section.artifacts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s (%d):\n", section.title, len(section.artifacts))
+		for _, a := range section.artifacts {
+			fmt.Fprintf(&b, "  - %s%s\n", a.Name, formatDigest(a.Digest))
+		}
+	}
+	for _, note := range s.Notes {
+		fmt.Fprintf(&b, "Note: %s\n", note)
+	}
+	return b.String()
+}
+
+// formatDigest renders a digest set as @algorithm:value, sorted by algorithm.
+func formatDigest(digest map[string]string) string {
+	algorithms := make([]string, 0, len(digest))
+	for algorithm := range digest {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+	var b strings.Builder
+	for _, algorithm := range algorithms {
+		fmt.Fprintf(&b, "@%s:%s", algorithm, digest[algorithm])
+	}
+	return b.String()
+}
@@ -0,0 +1,365 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// dssePAE returns the DSSE pre-authentication encoding of a payload, written independently of
+// verifyEnvelope.
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte("DSSEv1 " + fmt.Sprint(len(payloadType)) + " " + payloadType + " " + fmt.Sprint(len(payload)) + " " + string(payload))
+}
+
+func TestVerifySignature(t *testing.T) {
+	message := []byte("DSSEv1 28 application/vnd.in-toto+json 2 {}")
+	digest := sha256.Sum256(message)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaSig, err := ecdsa.SignASN1(rand.Reader, ecdsaKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1Sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pssSig, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Public, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		publicKey crypto.PublicKey
+		message   []byte
+		sig       []byte
+		wantErr   string
+	}{
+		{name: "ECDSA", publicKey: &ecdsaKey.PublicKey, message: message, sig: ecdsaSig},
+		{name: "RSA PKCS#1 v1.5", publicKey: &rsaKey.PublicKey, message: message, sig: pkcs1Sig},
+		{name: "RSA PSS", publicKey: &rsaKey.PublicKey, message: message, sig: pssSig},
+		{name: "ed25519", publicKey: ed25519Public, message: message, sig: ed25519.Sign(ed25519Key, message)},
+		{name: "ECDSA other key", publicKey: &otherKey.PublicKey, message: message, sig: ecdsaSig, wantErr: "ECDSA signature does not match the public key"},
+		{name: "ECDSA other message", publicKey: &ecdsaKey.PublicKey, message: []byte("other"), sig: ecdsaSig, wantErr: "ECDSA signature does not match the public key"},
+		{name: "RSA other message", publicKey: &rsaKey.PublicKey, message: []byte("other"), sig: pkcs1Sig, wantErr: "RSA signature does not match the public key"},
+		{name: "ed25519 other message", publicKey: ed25519Public, message: []byte("other"), sig: ed25519.Sign(ed25519Key, message), wantErr: "ed25519 signature does not match the public key"},
+		{name: "unsupported key", publicKey: "key", message: message, sig: ecdsaSig, wantErr: "unsupported public key type string"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifySignature(test.publicKey, test.message, test.sig)
+			if test.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), test.wantErr)) {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "chains"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pem     string
+		wantErr string
+	}{
+		{name: "public key", pem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))},
+		{name: "certificate", pem: "\n" + string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}))},
+		{name: "not PEM", pem: "cosign.pub", wantErr: "no PEM block found"},
+		{name: "private key", pem: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), wantErr: "PEM block PRIVATE KEY is not a public key or a certificate"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parsePublicKey(test.pem)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("got error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !key.PublicKey.Equal(got) {
+				t.Errorf("got key %v, want %v", got, key.PublicKey)
+			}
+		})
+	}
+}
+
+func TestInspectProvenance(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statement := []byte(`{
+		"_type": "https://in-toto.io/Statement/v1",
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"subject": [{"name": "registry.example.com/app", "digest": {"sha256": "abc"}}],
+		"predicate": {
+			"buildDefinition": {
+				"buildType": "https://tekton.dev/chains/v2/slsa",
+				"resolvedDependencies": [{"uri": "git+https://github.com/org/app.git", "digest": {"sha1": "def"}}]
+			},
+			"runDetails": {
+				"builder": {"id": "https://tekton.dev/chains/v2"},
+				"metadata": {"startedOn": "2025-01-01T00:00:00Z", "finishedOn": "2025-01-01T00:05:00Z"}
+			}
+		}
+	}`)
+	const payloadType = "application/vnd.in-toto+json"
+	// envelope signs the payload in a DSSE envelope with the key, as Chains does for the in-toto formats
+	envelope := func(payload []byte) string {
+		digest := sha256.Sum256(dssePAE(payloadType, payload))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(map[string]any{
+			"payloadType": payloadType,
+			"payload":     base64.StdEncoding.EncodeToString(payload),
+			"signatures":  []any{map[string]any{"keyid": "chains", "sig": base64.StdEncoding.EncodeToString(sig)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	}
+	tektonPayload := []byte(`{"taskRef":{"name":"build"}}`)
+	tektonDigest := sha256.Sum256(tektonPayload)
+	tektonSig, err := ecdsa.SignASN1(rand.Reader, key, tektonDigest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(data []byte) string { return base64.StdEncoding.EncodeToString(data) }
+	const (
+		payloadKey   = "chains.tekton.dev/payload-taskrun-uid-1"
+		signatureKey = "chains.tekton.dev/signature-taskrun-uid-1"
+		certKey      = "chains.tekton.dev/cert-taskrun-uid-1"
+	)
+
+	slsa := provenanceSummary{
+		Run:           "tekton://taskrun/default/build",
+		Signed:        "true",
+		PayloadType:   payloadType,
+		Statement:     "https://in-toto.io/Statement/v1",
+		PredicateType: "https://slsa.dev/provenance/v1",
+		Builder:       "https://tekton.dev/chains/v2",
+		BuildType:     "https://tekton.dev/chains/v2/slsa",
+		StartedOn:     "2025-01-01T00:00:00Z",
+		FinishedOn:    "2025-01-01T00:05:00Z",
+		Subjects:      []provenanceArtifact{{Name: "registry.example.com/app", Digest: map[string]string{"sha256": "abc"}}},
+		Materials:     []provenanceArtifact{{Name: "git+https://github.com/org/app.git", Digest: map[string]string{"sha1": "def"}}},
+	}
+	with := func(s provenanceSummary, f func(*provenanceSummary)) provenanceSummary {
+		f(&s)
+		return s
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		publicKey   crypto.PublicKey
+		want        provenanceSummary
+		wantErr     string
+	}{{
+		name: "verified",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			payloadKey:                encode(statement),
+			signatureKey:              envelope(statement),
+		},
+		publicKey: &key.PublicKey,
+		want:      with(slsa, func(s *provenanceSummary) { s.Signature = signatureCheck{Result: "verified", KeyID: "chains"} }),
+	}, {
+		name: "invalid",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			signatureKey:              envelope(statement),
+		},
+		publicKey: &otherKey.PublicKey,
+		want: with(slsa, func(s *provenanceSummary) {
+			s.Signature = signatureCheck{Result: "invalid", KeyID: "chains", Error: "ECDSA signature does not match the public key"}
+		}),
+	}, {
+		name: "unverified",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			signatureKey:              envelope(statement),
+		},
+		want: with(slsa, func(s *provenanceSummary) { s.Signature = signatureCheck{Result: "unverified", KeyID: "chains"} }),
+	}, {
+		name: "payload annotation differs from the envelope",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			payloadKey:                encode([]byte(`{"_type":"https://in-toto.io/Statement/v1"}`)),
+			signatureKey:              envelope(statement),
+		},
+		publicKey: &key.PublicKey,
+		want: with(slsa, func(s *provenanceSummary) {
+			s.Signature = signatureCheck{Result: "verified", KeyID: "chains"}
+			s.Notes = []string{"the payload annotation differs from the payload of the signed DSSE envelope, the signed payload is summarized"}
+		}),
+	}, {
+		name: "keyless certificate",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			signatureKey:              envelope(statement),
+			certKey:                   "LS0tLS1CRUdJTi...",
+		},
+		want: with(slsa, func(s *provenanceSummary) {
+			s.Signature = signatureCheck{Result: "unverified", KeyID: "chains"}
+			s.Notes = []string{"the run was signed with a keyless certificate, verify it with cosign against the certificate and the transparency log"}
+		}),
+	}, {
+		name: "missing signature",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			payloadKey:                encode(statement),
+		},
+		publicKey: &key.PublicKey,
+		want: with(slsa, func(s *provenanceSummary) {
+			s.PayloadType = ""
+			s.Signature = signatureCheck{Result: "missing"}
+		}),
+	}, {
+		name: "signing failed",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "failed",
+		},
+		want: provenanceSummary{
+			Run:       "tekton://taskrun/default/build",
+			Signed:    "failed",
+			Signature: signatureCheck{Result: "missing"},
+			Notes: []string{
+				`Tekton Chains did not sign the run, chains.tekton.dev/signed is "failed"`,
+				"the provenance is not stored on the run, Chains may store it in an OCI registry or another storage backend",
+			},
+		},
+	}, {
+		name: "not an in-toto payload",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			payloadKey:                encode(tektonPayload),
+			signatureKey:              encode(tektonSig),
+		},
+		publicKey: &key.PublicKey,
+		want: provenanceSummary{
+			Run:       "tekton://taskrun/default/build",
+			Signed:    "true",
+			Signature: signatureCheck{Result: "verified"},
+			Notes:     []string{"the payload is not an in-toto statement (e.g. the tekton format), it has no provenance to summarize"},
+		},
+	}, {
+		name: "not an in-toto payload with another key",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			payloadKey:                encode(tektonPayload),
+			signatureKey:              encode(tektonSig),
+		},
+		publicKey: &otherKey.PublicKey,
+		want: provenanceSummary{
+			Run:       "tekton://taskrun/default/build",
+			Signed:    "true",
+			Signature: signatureCheck{Result: "invalid", Error: "ECDSA signature does not match the public key"},
+			Notes:     []string{"the payload is not an in-toto statement (e.g. the tekton format), it has no provenance to summarize"},
+		},
+	}, {
+		name: "signature without payload",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			signatureKey:              encode(tektonSig),
+		},
+		wantErr: "the run has a signature but no payload annotation",
+	}, {
+		name:        "not processed by Chains",
+		annotations: map[string]string{"team": "a"},
+		wantErr:     "the run has no chains.tekton.dev/signed annotation, Tekton Chains did not process it",
+	}, {
+		name: "invalid payload annotation",
+		annotations: map[string]string{
+			ChainsSignedAnnotationKey: "true",
+			payloadKey:                "not base64!",
+		},
+		wantErr: "the payload annotation is not valid base64",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			k, _ := listKindOf("taskrun")
+			run := &v1.TaskRun{ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "default", UID: "uid-1", Annotations: test.annotations}}
+			got, err := inspectProvenance(k, run, test.publicKey)
+			if test.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.wantErr) {
+					t.Errorf("got error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"sigs.k8s.io/yaml"
+)
+
+// Config restricts what the server exposes to its clients.
+type Config struct {
+	// ReadOnly only exposes the tools that don't create or modify cluster objects.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// Tools lists the tools exposed to the clients.
+	Tools Filter `json:"tools,omitempty"`
+	// Namespaces lists the namespaces the tools and resources can access.
+	Namespaces Filter `json:"namespaces,omitempty"`
+	// DefaultNamespace is the namespace used when a tool call doesn't specify one,
+	// defaults to the namespace of the kubeconfig context.
+	DefaultNamespace string `json:"defaultNamespace,omitempty"`
+	// MaxResources is the maximum number of objects listed by resources/list, defaults to 500.
+	MaxResources int `json:"maxResources,omitempty"`
+}
+
+// Filter allows and denies names, as exact names or shell patterns (e.g. list_*), or a regular expression.
+// Deny takes precedence over Allow and AllowRegex, and if both are empty every name is allowed.
+type Filter struct {
+	Allow      []string `json:"allow,omitempty"`
+	AllowRegex string   `json:"allowRegex,omitempty"`
+	Deny       []string `json:"deny,omitempty"`
+
+	allowRegex *regexp.Regexp
+}
+
+// Allowed returns true if the name is allowed by the filter.
+func (f Filter) Allowed(name string) bool {
+	if matchAny(f.Deny, name) {
+		return false
+	}
+	if len(f.Allow) == 0 && f.allowRegex == nil {
+		return true
+	}
+	return matchAny(f.Allow, name) || (f.allowRegex != nil && f.allowRegex.MatchString(name))
+}
+
+// compile validates the patterns of the filter and compiles its regular expression.
+func (f *Filter) compile() error {
+	for _, patterns := range [][]string{f.Allow, f.Deny} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	if f.AllowRegex != "" {
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", f.AllowRegex))
+		if err != nil {
+			return fmt.Errorf("invalid regular expression %q: %w", f.AllowRegex, err)
+		}
+		f.allowRegex = re
+	}
+	return nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mutatingTools are the tools that create or modify cluster objects, hidden in read-only mode.
+var mutatingTools = map[string]bool{
+	"start_pipeline":     true,
+	"start_task":         true,
+	"start_customrun":    true,
+	"cancel_pipelinerun": true,
+	"cancel_taskrun":     true,
+	"rerun":              true,
+}
+
+// mutatingArguments are the boolean arguments that make a call to a read-only tool create or modify
+// cluster objects, such calls are rejected in read-only mode.
+var mutatingArguments = map[string]string{
+	"simulate_trigger": "create",
+}
+
+// LoadConfig reads the configuration from a YAML (or JSON) file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration %s: %w", file, err)
+	}
+	if err := cfg.Tools.compile(); err != nil {
+		return nil, fmt.Errorf("configuration %s, tools: %w", file, err)
+	}
+	if err := cfg.Namespaces.compile(); err != nil {
+		return nil, fmt.Errorf("configuration %s, namespaces: %w", file, err)
+	}
+	return cfg, nil
+}
+
+type configKey struct{}
+
+// WithConfig stores the configuration in the context, for the handlers to enforce it.
+func WithConfig(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// ConfigFromContext returns the configuration of the server, or an empty configuration if none is set.
+func ConfigFromContext(ctx context.Context) *Config {
+	if cfg, ok := ctx.Value(configKey{}).(*Config); ok {
+		return cfg
+	}
+	return &Config{}
+}
+
+// CheckNamespace returns an error if the namespace is not allowed.
+func (c *Config) CheckNamespace(namespace string) error {
+	if !c.Namespaces.Allowed(namespace) {
+		return fmt.Errorf("namespace %s is not allowed on this server", namespace)
+	}
+	return nil
+}
+
+// Namespace returns the namespace to use for a requested namespace: the default namespace if none is
+// requested. It returns an error if the namespace is not allowed.
+func (c *Config) Namespace(requested string) (string, error) {
+	namespace := requested
+	if namespace == "" {
+		namespace = c.DefaultNamespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return namespace, c.CheckNamespace(namespace)
+}
+
+// requestNamespace returns the namespace targeted by a tool call, from its "namespace" argument or the
+// default namespace. It returns an error if the namespace is not allowed.
+func requestNamespace(ctx context.Context, request mcp.CallToolRequest) (string, error) {
+	namespace, err := OptionalParam[string](request, "namespace")
+	if err != nil {
+		return "", err
+	}
+	return ConfigFromContext(ctx).Namespace(namespace)
+}
+
+// listNamespace returns the namespace targeted by a list tool call, empty to list all the allowed
+// namespaces. It returns an error if the namespace is not allowed.
+func listNamespace(ctx context.Context, request mcp.CallToolRequest) (string, error) {
+	namespace, err := OptionalParam[string](request, "namespace")
+	if err != nil || namespace == "" {
+		return namespace, err
+	}
+	return namespace, ConfigFromContext(ctx).CheckNamespace(namespace)
+}
+
+// ToolEnabled returns true if the tool is exposed to the clients.
+func (c *Config) ToolEnabled(name string) bool {
+	if c.ReadOnly && mutatingTools[name] {
+		return false
+	}
+	return c.Tools.Allowed(name)
+}
+
+// RestrictToolCall is a tool middleware rejecting the calls to disabled tools. The namespaces are
+// checked by the handlers, which know whether a call targets a single namespace.
+func (c *Config) RestrictToolCall(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !c.ToolEnabled(request.Params.Name) {
+			return mcpError(fmt.Sprintf("Tool %s is disabled on this server", request.Params.Name)), nil
+		}
+		if arg, ok := mutatingArguments[request.Params.Name]; ok && c.ReadOnly {
+			if mutating, _ := request.Params.Arguments[arg].(bool); mutating {
+				return mcpError(fmt.Sprintf("Tool %s can't be called with %s on this server, it is read-only", request.Params.Name, arg)), nil
+			}
+		}
+		return next(ctx, request)
+	}
+}
@@ -0,0 +1,87 @@
+package internal
+
+import "testing"
+
+func TestFilterAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		allowed []string
+		denied  []string
+	}{{
+		name:    "empty filter",
+		allowed: []string{"list_pipelineruns", "default"},
+	}, {
+		name:    "allow",
+		filter:  Filter{Allow: []string{"list_*", "get_logs"}},
+		allowed: []string{"list_pipelineruns", "get_logs"},
+		denied:  []string{"start_pipeline", "get_logs_x"},
+	}, {
+		name:    "deny",
+		filter:  Filter{Deny: []string{"start_*"}},
+		allowed: []string{"list_pipelineruns"},
+		denied:  []string{"start_pipeline", "start_task"},
+	}, {
+		name:    "deny wins over allow",
+		filter:  Filter{Allow: []string{"team-*"}, Deny: []string{"team-secret"}},
+		allowed: []string{"team-a"},
+		denied:  []string{"team-secret", "other"},
+	}, {
+		name:    "deny wins over allowRegex",
+		filter:  Filter{AllowRegex: "team-.*", Deny: []string{"team-secret"}},
+		allowed: []string{"team-a"},
+		denied:  []string{"team-secret"},
+	}, {
+		name:    "allowRegex is anchored",
+		filter:  Filter{AllowRegex: "ci-[0-9]+"},
+		allowed: []string{"ci-1", "ci-42"},
+		denied:  []string{"ci-1x", "xci-1", "my-ci-1-test", "ci-"},
+	}, {
+		name:    "allowRegex alternatives are anchored",
+		filter:  Filter{AllowRegex: "dev|ci"},
+		allowed: []string{"dev", "ci"},
+		denied:  []string{"devops", "oci"},
+	}, {
+		name:    "allow or allowRegex",
+		filter:  Filter{Allow: []string{"team-*"}, AllowRegex: "ci-[0-9]+"},
+		allowed: []string{"team-a", "ci-1"},
+		denied:  []string{"default"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.filter.compile(); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range test.allowed {
+				if !test.filter.Allowed(name) {
+					t.Errorf("%s is denied, want allowed", name)
+				}
+			}
+			for _, name := range test.denied {
+				if test.filter.Allowed(name) {
+					t.Errorf("%s is allowed, want denied", name)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantErr bool
+	}{
+		{name: "valid", filter: Filter{Allow: []string{"list_*"}, AllowRegex: "ci-[0-9]+", Deny: []string{"start_?"}}},
+		{name: "invalid allow pattern", filter: Filter{Allow: []string{"list_["}}, wantErr: true},
+		{name: "invalid deny pattern", filter: Filter{Deny: []string{"["}}, wantErr: true},
+		{name: "invalid regular expression", filter: Filter{AllowRegex: "ci-("}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.filter.compile(); (err != nil) != test.wantErr {
+				t.Errorf("compile() = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func toolStartCustomRun() mcp.Tool {
+	return mcp.NewTool("start_customrun",
+		mcp.WithDescription("Start a custom task (e.g. an approval or a wait task) by creating a CustomRun"),
+		mcp.WithString("api-version", mcp.Required(),
+			mcp.Description("API version of the custom task, e.g. openshift-pipelines.org/v1alpha1"),
+		),
+		mcp.WithString("kind", mcp.Required(),
+			mcp.Description("Kind of the custom task, e.g. ApprovalTask"),
+		),
+		mcp.WithString("name",
+			mcp.Description("Name of the custom task object to run, for the custom tasks that have one"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace where to create the CustomRun, defaults to the server's default namespace"),
+		),
+		mcp.WithObject("params",
+			mcp.Description("Parameters of the custom task, as an object mapping each param name to a string, an array of strings or an object with string values"),
+		),
+		mcp.WithString("service-account",
+			mcp.Description("ServiceAccount used to run the custom task"),
+		),
+		mcp.WithString("timeout",
+			mcp.Description("Timeout of the CustomRun, as a duration (e.g. 1h30m)"),
+		),
+		mcp.WithBoolean("wait",
+			mcp.Description("Wait for the CustomRun to finish and return its final status and results"),
+		),
+		mcp.WithString("wait-timeout",
+			mcp.Description("How long to wait for the CustomRun to finish, as a duration (e.g. 30m), defaults to 10m"),
+		),
+	)
+}
+
+func handlerStartCustomRun(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var errs []string
+	apiVersion, err := OptionalParam[string](request, "api-version")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	kind, err := OptionalParam[string](request, "kind")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if apiVersion == "" || kind == "" {
+		errs = append(errs, "api-version and kind are required")
+	}
+	name, err := OptionalParam[string](request, "name")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	runParams, paramErrs := parseRunParams(request)
+	errs = append(errs, paramErrs...)
+	serviceAccount, err := OptionalParam[string](request, "service-account")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	var timeout *metav1.Duration
+	if _, err := DecodeParam(request, "timeout", &timeout); err != nil {
+		errs = append(errs, err.Error())
+	}
+	wait, waitTimeout, err := waitParams(request)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	customTask := kind
+	if name != "" {
+		customTask = fmt.Sprintf("%s %s", kind, name)
+	}
+	if len(errs) > 0 {
+		return invalidRunError("custom task", namespace, customTask, errs), nil
+	}
+
+	params := make(v1beta1.Params, len(runParams))
+	for i, p := range runParams {
+		params[i].ConvertFrom(ctx, p)
+	}
+
+	generateName := strings.ToLower(kind)
+	if name != "" {
+		generateName = name
+	}
+	cr := &v1beta1.CustomRun{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "tekton.dev/v1beta1",
+			Kind:       "CustomRun",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    namespace,
+			GenerateName: fmt.Sprintf("%s-", generateName),
+		},
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: &v1beta1.TaskRef{
+				APIVersion: apiVersion,
+				Kind:       v1beta1.TaskKind(kind),
+				Name:       name,
+			},
+			Params:             params,
+			ServiceAccountName: serviceAccount,
+			Timeout:            timeout,
+		},
+	}
+
+	created, err := pipelineclient.Get(ctx).TektonV1beta1().CustomRuns(namespace).Create(ctx, cr, metav1.CreateOptions{})
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to create CustomRun of %s in %s: %v", customTask, namespace, err)), nil
+	}
+
+	if !wait {
+		return runResult("customrun", created, fmt.Sprintf("Started custom task %s", customTask), nil)
+	}
+
+	run, done, err := waitForCustomRun(ctx, namespace, created.Name, waitTimeout)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to wait for CustomRun %s/%s: %v", namespace, created.Name, err)), nil
+	}
+	outcome := customRunOutcome(run)
+	return runResult("customrun", run, waitedRunSummary("CustomRun", outcome, done), outcome)
+}
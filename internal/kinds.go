@@ -0,0 +1,276 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	pipelineinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipeline"
+	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
+	taskinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/task"
+	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	stepactioninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/stepaction"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// listKind is a kind listed from an informer cache. Registering a kind in listKinds adds its
+// list_<type>s tool, with the same filtering, pagination and output arguments as every other kind.
+type listKind struct {
+	// kind is the kind of the objects, e.g. PipelineRun.
+	kind string
+	// resourceType is the type of the objects in tool names and tekton:// URIs, e.g. pipelinerun.
+	resourceType string
+	// group is the API group of the objects, used to authorize the callers.
+	group string
+	// spec describes the sorting, output and filter arguments of the list tool.
+	spec listSpec
+	// informer returns the informer caching the objects.
+	informer func(ctx context.Context) cache.SharedIndexInformer
+}
+
+// resource returns the plural resource name of the kind, e.g. pipelineruns.
+func (k listKind) resource() string {
+	return fmt.Sprintf("%ss", k.resourceType)
+}
+
+// toolName returns the name of the list tool of the kind.
+func (k listKind) toolName() string {
+	return fmt.Sprintf("list_%s", k.resource())
+}
+
+// listKinds are the kinds that can be listed.
+var listKinds = []listKind{{
+	kind:         "PipelineRun",
+	resourceType: "pipelinerun",
+	group:        "tekton.dev",
+	spec:         pipelineRunList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return pipelineruninformer.Get(ctx).Informer()
+	},
+}, {
+	kind:         "TaskRun",
+	resourceType: "taskrun",
+	group:        "tekton.dev",
+	spec:         taskRunList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return taskruninformer.Get(ctx).Informer()
+	},
+}, {
+	kind:         "CustomRun",
+	resourceType: "customrun",
+	group:        "tekton.dev",
+	spec:         customRunList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return customruninformer.Get(ctx).Informer()
+	},
+}, {
+	kind:         "Pipeline",
+	resourceType: "pipeline",
+	group:        "tekton.dev",
+	spec:         definitionList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return pipelineinformer.Get(ctx).Informer()
+	},
+}, {
+	kind:         "Task",
+	resourceType: "task",
+	group:        "tekton.dev",
+	spec:         definitionList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return taskinformer.Get(ctx).Informer()
+	},
+}, {
+	kind:         "StepAction",
+	resourceType: "stepaction",
+	group:        "tekton.dev",
+	spec:         definitionList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return stepactioninformer.Get(ctx).Informer()
+	},
+}, {
+	kind:         "EventListener",
+	resourceType: "eventlistener",
+	group:        triggersGroup,
+	spec:         definitionList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return triggersInformer(ctx, "eventlisteners")
+	},
+}, {
+	kind:         "Trigger",
+	resourceType: "trigger",
+	group:        triggersGroup,
+	spec:         definitionList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return triggersInformer(ctx, "triggers")
+	},
+}, {
+	kind:         "TriggerBinding",
+	resourceType: "triggerbinding",
+	group:        triggersGroup,
+	spec:         definitionList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return triggersInformer(ctx, "triggerbindings")
+	},
+}, {
+	kind:         "TriggerTemplate",
+	resourceType: "triggertemplate",
+	group:        triggersGroup,
+	spec:         definitionList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return triggersInformer(ctx, "triggertemplates")
+	},
+}, {
+	kind:         "Interceptor",
+	resourceType: "interceptor",
+	group:        triggersGroup,
+	spec:         definitionList,
+	informer: func(ctx context.Context) cache.SharedIndexInformer {
+		return triggersInformer(ctx, "interceptors")
+	},
+}}
+
+func init() {
+	for _, k := range listKinds {
+		toolAccess[k.toolName()] = staticAccess(access{verb: "list", group: k.group, resource: k.resource()})
+	}
+}
+
+// listCached returns the objects of the informer cache matching the selector, in a namespace or in
+// all namespaces if namespace is empty.
+func listCached(informer cache.SharedIndexInformer, namespace string, selector labels.Selector) ([]metav1.Object, error) {
+	var objs []metav1.Object
+	var errs []error
+	appendObject := func(obj any) {
+		o, ok := obj.(metav1.Object)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unexpected object %T in the informer cache", obj))
+			return
+		}
+		objs = append(objs, o)
+	}
+
+	var err error
+	if namespace == "" {
+		err = cache.ListAll(informer.GetIndexer(), selector, appendObject)
+	} else {
+		err = cache.ListAllByNamespace(informer.GetIndexer(), namespace, selector, appendObject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return objs, nil
+}
+
+// listTool returns the list tool of the kind.
+func (k listKind) listTool() mcp.Tool {
+	plural := fmt.Sprintf("%ss", k.kind)
+	opts := []mcp.ToolOption{
+		mcp.WithDescription(fmt.Sprintf("List %s in the cluster with filtering options", strings.ToLower(plural))),
+		mcp.WithString("namespace", mcp.Description(fmt.Sprintf("Which namespace to use to look for %s, defaults to all the allowed namespaces", plural))),
+		mcp.WithString("prefix", mcp.Description(fmt.Sprintf("Name prefix to filter %s", plural))),
+		mcp.WithString("label-selector", mcp.Description(fmt.Sprintf("Label selector to filter %s", plural))),
+	}
+	return mcp.NewTool(k.toolName(), append(opts, listToolOptions(k.spec)...)...)
+}
+
+// handlerList returns the handler of the list tool of the kind.
+func (k listKind) handlerList() server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		namespace, err := listNamespace(ctx, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		lselector, err := OptionalParam[string](request, "label-selector")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		prefix, err := OptionalParam[string](request, "prefix")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		opts, err := listParams(request, k.spec)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		selector := labels.Everything()
+		if lselector != "" {
+			selector, err = labels.Parse(lselector)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+
+		objs, err := listCached(k.informer(ctx), namespace, selector)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// The runs deleted from the cluster are listed from Tekton Results, when configured
+		var notes []string
+		archived, err := listArchived(ctx, k, namespace, prefix, selector, objs)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("Failed to read Tekton Results, archived runs may be missing: %v", err))
+		}
+		if archived.truncated {
+			notes = append(notes, fmt.Sprintf("Only the %d most recent records of Tekton Results were read, older archived runs are missing", maxResultsRecords))
+		}
+		objs = append(objs, archived.objs...)
+
+		// Filter after the fact, the namespaces denied by the configuration are never listed
+		namespaces := ConfigFromContext(ctx).Namespaces
+		filtered := []metav1.Object{}
+		for _, obj := range objs {
+			if strings.HasPrefix(obj.GetName(), prefix) && namespaces.Allowed(obj.GetNamespace()) && opts.filter.matches(obj) {
+				filtered = append(filtered, obj)
+			}
+		}
+
+		page, next, remaining := paginate(filtered, opts)
+		result, err := listResult(page, next, remaining, opts.output, archived.records)
+		if err != nil || result.IsError {
+			return result, err
+		}
+		for _, note := range notes {
+			result.Content = append(result.Content, mcp.NewTextContent(note))
+		}
+		return result, nil
+	}
+}
+
+// listKindOf returns the listed kind of a resource type, e.g. pipelinerun.
+func listKindOf(resourceType string) (listKind, bool) {
+	for _, k := range listKinds {
+		if k.resourceType == resourceType {
+			return k, true
+		}
+	}
+	return listKind{}, false
+}
+
+// getCached returns an object of the kind from its informer cache.
+func (k listKind) getCached(ctx context.Context, namespace, name string) (runtime.Object, error) {
+	obj, exists, err := k.informer(ctx).GetIndexer().GetByKey(fmt.Sprintf("%s/%s", namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: k.group, Resource: k.resource()}, name)
+	}
+	o, ok := obj.(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T in the informer cache", obj)
+	}
+	return o, nil
+}
@@ -0,0 +1,339 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// defaultListLimit is the number of objects returned by a list tool when no limit is given.
+	defaultListLimit = 100
+	// maxListLimit is the maximum number of objects returned by a list tool in one call.
+	maxListLimit = 1000
+)
+
+// listSpec describes the sorting and output arguments of a list tool, which depend on the listed kind.
+type listSpec struct {
+	// sortFields are the fields the objects can be sorted by, the first one is the default.
+	sortFields []string
+	// defaultOutput is the output format used when none is requested.
+	defaultOutput string
+	// runRef is the argument filtering runs by the name of the referenced kind, runRefKind,
+	// empty for kinds that are not runs.
+	runRef, runRefKind string
+}
+
+// runSortFields are the fields runs can be sorted by.
+var runSortFields = []string{"creationTimestamp", "completionTime", "name", "duration"}
+
+var (
+	// definitionList lists definitions (Pipelines, Tasks...), in full by default.
+	definitionList = listSpec{
+		sortFields:    []string{"name", "creationTimestamp"},
+		defaultOutput: "full",
+	}
+	// pipelineRunList lists PipelineRuns, summarized by default.
+	pipelineRunList = listSpec{
+		sortFields:    runSortFields,
+		defaultOutput: "summary",
+		runRef:        "pipeline",
+		runRefKind:    "Pipeline",
+	}
+	// taskRunList lists TaskRuns, summarized by default.
+	taskRunList = listSpec{
+		sortFields:    runSortFields,
+		defaultOutput: "summary",
+		runRef:        "task",
+		runRefKind:    "Task",
+	}
+	// customRunList lists CustomRuns, summarized by default.
+	customRunList = listSpec{
+		sortFields:    runSortFields,
+		defaultOutput: "summary",
+		runRef:        "custom-task",
+		runRefKind:    "custom task kind",
+	}
+)
+
+// listOptions are the pagination and sorting arguments of the list tools.
+type listOptions struct {
+	limit  int
+	sortBy string
+	order  string
+	after  *listKey
+	output outputFormat
+	filter runFilter
+}
+
+// listKey is the position of an object in a sorted list. Objects are ordered by Num, then Str, and
+// then by namespace and name so that the order is total and pages don't overlap.
+type listKey struct {
+	Num       int64  `json:"n,omitempty"`
+	Str       string `json:"v,omitempty"`
+	Namespace string `json:"ns,omitempty"`
+	Name      string `json:"name"`
+}
+
+// listCursor is the content of the opaque cursor returned to get the next page of a list.
+type listCursor struct {
+	SortBy string  `json:"sortBy"`
+	Order  string  `json:"order"`
+	After  listKey `json:"after"`
+}
+
+// listToolOptions returns the pagination, sorting and output arguments of a list tool, and the
+// filter arguments of the run list tools.
+func listToolOptions(spec listSpec) []mcp.ToolOption {
+	var opts []mcp.ToolOption
+	if spec.runRef != "" {
+		opts = runFilterToolOptions(spec.runRef, spec.runRefKind)
+	}
+	sortFields := spec.sortFields
+	return append(opts,
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of objects to return, defaults to %d, at most %d", defaultListLimit, maxListLimit)),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("Cursor returned by a previous call, to get the next page of results with the same filters and sorting"),
+		),
+		mcp.WithString("sort-by",
+			mcp.Description(fmt.Sprintf("Field to sort the objects by, defaults to %s", sortFields[0])),
+			mcp.Enum(sortFields...),
+		),
+		mcp.WithString("order",
+			mcp.Description("Sort order, defaults to asc when sorting by name and desc otherwise"),
+			mcp.Enum("asc", "desc"),
+		),
+		outputToolOption(spec.defaultOutput),
+	)
+}
+
+// listParams reads the pagination, sorting and output arguments of a list tool.
+func listParams(request mcp.CallToolRequest, spec listSpec) (listOptions, error) {
+	sortFields := spec.sortFields
+	opts := listOptions{limit: defaultListLimit, sortBy: sortFields[0]}
+
+	if _, ok := request.Params.Arguments["limit"]; ok {
+		limit, err := OptionalParam[float64](request, "limit")
+		if err != nil {
+			return opts, err
+		}
+		if limit < 1 || limit > maxListLimit || limit != math.Trunc(limit) {
+			return opts, fmt.Errorf("parameter limit must be an integer between 1 and %d", maxListLimit)
+		}
+		opts.limit = int(limit)
+	}
+
+	sortBy, err := OptionalParam[string](request, "sort-by")
+	if err != nil {
+		return opts, err
+	}
+	if sortBy != "" {
+		if !slices.Contains(sortFields, sortBy) {
+			return opts, fmt.Errorf("parameter sort-by must be one of %s", strings.Join(sortFields, ", "))
+		}
+		opts.sortBy = sortBy
+	}
+
+	order, err := OptionalParam[string](request, "order")
+	if err != nil {
+		return opts, err
+	}
+	switch order {
+	case "":
+		opts.order = "desc"
+		if opts.sortBy == "name" {
+			opts.order = "asc"
+		}
+	case "asc", "desc":
+		opts.order = order
+	default:
+		return opts, fmt.Errorf("parameter order must be asc or desc")
+	}
+
+	cursor, err := OptionalParam[string](request, "cursor")
+	if err != nil {
+		return opts, err
+	}
+	if cursor != "" {
+		c, err := decodeListCursor(cursor)
+		if err != nil {
+			return opts, err
+		}
+		if c.SortBy != opts.sortBy || c.Order != opts.order {
+			return opts, fmt.Errorf("the cursor was returned for sort-by %s and order %s, not %s and %s", c.SortBy, c.Order, opts.sortBy, opts.order)
+		}
+		opts.after = &c.After
+	}
+
+	opts.output, err = outputParams(request, spec.defaultOutput)
+	if err != nil {
+		return opts, err
+	}
+
+	if spec.runRef != "" {
+		opts.filter, err = runFilterParams(request, spec.runRef)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+func encodeListCursor(c listCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(cursor string) (listCursor, error) {
+	var c listCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("parameter cursor is not a cursor returned by this tool")
+	}
+	return c, nil
+}
+
+// sortKey returns the position of an object when sorting by the given field. Runs that are not
+// finished sort after the finished ones by completionTime and duration, in ascending order.
+func sortKey(obj metav1.Object, sortBy string) listKey {
+	key := listKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	switch sortBy {
+	case "name":
+		key.Str = obj.GetName()
+	case "creationTimestamp":
+		key.Num = obj.GetCreationTimestamp().UnixNano()
+	case "completionTime", "duration":
+		start, completion := runTimes(obj)
+		switch {
+		case completion == nil:
+			key.Num = math.MaxInt64
+		case sortBy == "completionTime":
+			key.Num = completion.UnixNano()
+		case start == nil:
+			key.Num = math.MaxInt64
+		default:
+			key.Num = int64(completion.Sub(start.Time))
+		}
+	}
+	return key
+}
+
+// runTimes returns the start and completion times of a PipelineRun, TaskRun or CustomRun, nil for
+// other objects.
+func runTimes(obj metav1.Object) (*metav1.Time, *metav1.Time) {
+	switch run := obj.(type) {
+	case *v1.PipelineRun:
+		return run.Status.StartTime, run.Status.CompletionTime
+	case *v1.TaskRun:
+		return run.Status.StartTime, run.Status.CompletionTime
+	case *v1beta1.CustomRun:
+		return run.Status.StartTime, run.Status.CompletionTime
+	}
+	return nil, nil
+}
+
+func compareListKeys(a, b listKey) int {
+	return cmp.Or(
+		cmp.Compare(a.Num, b.Num),
+		cmp.Compare(a.Str, b.Str),
+		cmp.Compare(a.Namespace, b.Namespace),
+		cmp.Compare(a.Name, b.Name),
+	)
+}
+
+// paginate sorts the objects and returns the page selected by the options, with the cursor of the
+// next page (empty on the last page) and the number of objects after the page.
+func paginate[T metav1.Object](objs []T, opts listOptions) ([]T, string, int) {
+	type entry struct {
+		obj T
+		key listKey
+	}
+	compare := func(a, b listKey) int {
+		if opts.order == "desc" {
+			return compareListKeys(b, a)
+		}
+		return compareListKeys(a, b)
+	}
+
+	entries := make([]entry, 0, len(objs))
+	for _, obj := range objs {
+		key := sortKey(obj, opts.sortBy)
+		if opts.after != nil && compare(key, *opts.after) <= 0 {
+			continue
+		}
+		entries = append(entries, entry{obj: obj, key: key})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return compare(a.key, b.key)
+	})
+
+	page := make([]T, 0, min(opts.limit, len(entries)))
+	for _, e := range entries[:min(opts.limit, len(entries))] {
+		page = append(page, e.obj)
+	}
+	remaining := len(entries) - len(page)
+	if remaining == 0 {
+		return page, "", 0
+	}
+	next := encodeListCursor(listCursor{
+		SortBy: opts.sortBy,
+		Order:  opts.order,
+		After:  entries[len(page)-1].key,
+	})
+	return page, next, remaining
+}
+
+// listResult returns a page of objects in the requested output format, telling how to get the next
+// page if there are more objects, and which objects were read from Tekton Results, by their records
+// keyed by UID.
+func listResult[T metav1.Object](page []T, next string, remaining int, format outputFormat, records map[types.UID]string) (*mcp.CallToolResult, error) {
+	var text string
+	if format.mode == "yaml" {
+		yamlData, err := objectsYAML(page)
+		if err != nil {
+			return nil, err
+		}
+		text = yamlData
+	} else {
+		rendered, err := formatObjects(page, format, records)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		jsonData, err := json.Marshal(rendered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
+		}
+		text = string(jsonData)
+	}
+
+	result := mcp.NewToolResultText(text)
+	var archived []string
+	for _, obj := range page {
+		if record := records[obj.GetUID()]; record != "" {
+			archived = append(archived, fmt.Sprintf("%s/%s (record %s)", obj.GetNamespace(), obj.GetName(), record))
+		}
+	}
+	if len(archived) > 0 {
+		result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("%d objects are no longer in the cluster and were read from Tekton Results: %s", len(archived), strings.Join(archived, ", "))))
+	}
+	if next != "" {
+		result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("%d more objects match, call the tool again with cursor %q to get the next page", remaining, next)))
+	}
+	return result, nil
+}
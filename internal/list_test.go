@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPaginate(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(namespace, name string, created, duration time.Duration) *v1.PipelineRun {
+		pr := &v1.PipelineRun{ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace, Name: name, CreationTimestamp: metav1.NewTime(base.Add(created)),
+		}}
+		if duration > 0 {
+			start, completion := metav1.NewTime(base.Add(created)), metav1.NewTime(base.Add(created+duration))
+			pr.Status.StartTime, pr.Status.CompletionTime = &start, &completion
+		}
+		return pr
+	}
+	runs := []*v1.PipelineRun{
+		run("b", "build", time.Minute, 3*time.Minute),
+		run("a", "build", time.Minute, time.Minute),
+		run("a", "deploy", 2*time.Minute, 0),
+		run("a", "test", 0, 2*time.Minute),
+	}
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want [][]string
+	}{{
+		name: "by creationTimestamp, newest first",
+		args: map[string]any{"limit": float64(2)},
+		want: [][]string{{"a/deploy", "b/build"}, {"a/build", "a/test"}},
+	}, {
+		name: "by name",
+		args: map[string]any{"limit": float64(3), "sort-by": "name"},
+		want: [][]string{{"a/build", "b/build", "a/deploy"}, {"a/test"}},
+	}, {
+		name: "by duration, running runs last",
+		args: map[string]any{"limit": float64(1), "sort-by": "duration", "order": "asc"},
+		want: [][]string{{"a/build"}, {"a/test"}, {"b/build"}, {"a/deploy"}},
+	}, {
+		name: "by completionTime, ties broken by name",
+		args: map[string]any{"sort-by": "completionTime"},
+		want: [][]string{{"a/deploy", "b/build", "a/test", "a/build"}},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got [][]string
+			args := test.args
+			for {
+				request := mcp.CallToolRequest{}
+				request.Params.Arguments = args
+				opts, err := listParams(request, pipelineRunList)
+				if err != nil {
+					t.Fatal(err)
+				}
+				page, next, remaining := paginate(runs, opts)
+				var names []string
+				for _, pr := range page {
+					names = append(names, pr.Namespace+"/"+pr.Name)
+				}
+				got = append(got, names)
+
+				total := 0
+				for _, p := range test.want[len(got):] {
+					total += len(p)
+				}
+				if remaining != total {
+					t.Errorf("page %d: %d remaining, want %d", len(got), remaining, total)
+				}
+				if next == "" || len(got) > len(test.want) {
+					break
+				}
+				// The cursor is passed back with the same arguments
+				args = map[string]any{"cursor": next}
+				for k, v := range test.args {
+					args[k] = v
+				}
+			}
+			if !slices.EqualFunc(got, test.want, slices.Equal) {
+				t.Errorf("got pages %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	c := listCursor{SortBy: "name", Order: "asc", After: listKey{Str: "build", Namespace: "a", Name: "build"}}
+	decoded, err := decodeListCursor(encodeListCursor(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != c {
+		t.Errorf("got %+v, want %+v", decoded, c)
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]any
+		wantErr string
+	}{{
+		name:    "not a cursor",
+		args:    map[string]any{"cursor": "not a cursor"},
+		wantErr: "parameter cursor is not a cursor returned by this tool",
+	}, {
+		name:    "other sorting",
+		args:    map[string]any{"cursor": encodeListCursor(c), "sort-by": "creationTimestamp"},
+		wantErr: "the cursor was returned for sort-by name and order asc, not creationTimestamp and desc",
+	}, {
+		name: "same sorting",
+		args: map[string]any{"cursor": encodeListCursor(c), "sort-by": "name"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = test.args
+			opts, err := listParams(request, definitionList)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("got error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.after == nil || *opts.after != c.After {
+				t.Errorf("got after %+v, want %+v", opts.after, c.After)
+			}
+		})
+	}
+}
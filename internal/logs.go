@@ -0,0 +1,456 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
+	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+)
+
+// defaultLogLimitBytes is the maximum number of bytes of logs returned per container by default.
+const defaultLogLimitBytes = 64 * 1024
+
+// maxLogReadBytes is the maximum number of bytes read from a container before truncating its logs.
+const maxLogReadBytes = 16 * 1024 * 1024
+
+// logOptions selects which logs are fetched for a TaskRun.
+type logOptions struct {
+	steps      []string
+	sidecars   bool
+	tailLines  *int64
+	sinceTime  *metav1.Time
+	limitBytes int
+}
+
+func toolGetLogs() mcp.Tool {
+	return mcp.NewTool("get_logs",
+		mcp.WithDescription("Get the logs of the steps of a TaskRun, or of all the TaskRuns of a PipelineRun in execution order"),
+		mcp.WithString("kind", mcp.Required(),
+			mcp.Description("Kind of the run to get the logs of"),
+			mcp.Enum("pipelinerun", "taskrun"),
+		),
+		mcp.WithString("name", mcp.Required(),
+			mcp.Description("Name of the run to get the logs of"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace where the run is located, defaults to the server's default namespace"),
+		),
+		mcp.WithArray("steps",
+			mcp.Description("Names of the steps (or sidecars) to get the logs of, defaults to all the steps"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithBoolean("sidecars",
+			mcp.Description("Also get the logs of the sidecars"),
+		),
+		mcp.WithNumber("tail-lines",
+			mcp.Description("Number of lines to get from the end of each container logs"),
+		),
+		mcp.WithString("since-time",
+			mcp.Description("Only get the logs written after this time (RFC3339)"),
+		),
+		mcp.WithNumber("limit-bytes",
+			mcp.Description("Maximum number of bytes of logs returned per container, keeping the end of the logs, defaults to 65536"),
+		),
+	)
+}
+
+func handlerGetLogs(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	kind, err := OptionalParam[string](request, "kind")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	name, err := OptionalParam[string](request, "name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	opts, err := logParams(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	k, ok := listKindOf(kind)
+	if ok && resultsRecordTypes[kind] != nil {
+		if _, err := k.getCached(ctx, namespace, name); apierrors.IsNotFound(err) {
+			// The run may have been deleted from the cluster and its logs stored by Tekton Results
+			logs, found, err := archivedLogs(ctx, k, namespace, name, opts)
+			if err != nil {
+				return mcpError(fmt.Sprintf("Failed to get logs of %s %s/%s from Tekton Results: %v", k.kind, namespace, name, err)), nil
+			}
+			if found {
+				return mcp.NewToolResultText(logs), nil
+			}
+		}
+	}
+
+	var logs string
+	switch kind {
+	case "pipelinerun":
+		pr, err := pipelineruninformer.Get(ctx).Lister().PipelineRuns(namespace).Get(name)
+		if err != nil {
+			return mcpError(fmt.Sprintf("Failed to get PipelineRun %s/%s: %v", namespace, name, err)), nil
+		}
+		logs, err = pipelineRunLogs(ctx, pr, opts)
+		if err != nil {
+			return mcpError(fmt.Sprintf("Failed to get logs of PipelineRun %s/%s: %v", namespace, name, err)), nil
+		}
+	case "taskrun":
+		tr, err := taskruninformer.Get(ctx).Lister().TaskRuns(namespace).Get(name)
+		if err != nil {
+			return mcpError(fmt.Sprintf("Failed to get TaskRun %s/%s: %v", namespace, name, err)), nil
+		}
+		logs = taskRunLogs(ctx, tr, opts)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("kind %q is not one of pipelinerun or taskrun", kind)), nil
+	}
+
+	return mcp.NewToolResultText(logs), nil
+}
+
+// logParams reads the log selection parameters of the get_logs tool.
+func logParams(request mcp.CallToolRequest) (logOptions, error) {
+	opts := logOptions{limitBytes: defaultLogLimitBytes}
+
+	steps, err := OptionalParam[[]any](request, "steps")
+	if err != nil {
+		return opts, err
+	}
+	for _, step := range steps {
+		s, ok := step.(string)
+		if !ok {
+			return opts, fmt.Errorf("parameter steps must only contain strings, contains %T", step)
+		}
+		opts.steps = append(opts.steps, s)
+	}
+
+	opts.sidecars, err = OptionalParam[bool](request, "sidecars")
+	if err != nil {
+		return opts, err
+	}
+
+	if _, ok := request.Params.Arguments["tail-lines"]; ok {
+		tailLines, err := OptionalParam[float64](request, "tail-lines")
+		if err != nil {
+			return opts, err
+		}
+		if tailLines < 0 || tailLines != math.Trunc(tailLines) {
+			return opts, fmt.Errorf("parameter tail-lines must be a non-negative integer")
+		}
+		lines := int64(tailLines)
+		opts.tailLines = &lines
+	}
+
+	sinceTime, err := OptionalParam[string](request, "since-time")
+	if err != nil {
+		return opts, err
+	}
+	if sinceTime != "" {
+		t, err := time.Parse(time.RFC3339, sinceTime)
+		if err != nil {
+			return opts, fmt.Errorf("parameter since-time is not a valid RFC3339 time: %w", err)
+		}
+		opts.sinceTime = &metav1.Time{Time: t}
+	}
+
+	if _, ok := request.Params.Arguments["limit-bytes"]; ok {
+		limitBytes, err := OptionalParam[float64](request, "limit-bytes")
+		if err != nil {
+			return opts, err
+		}
+		if limitBytes < 0 || limitBytes != math.Trunc(limitBytes) {
+			return opts, fmt.Errorf("parameter limit-bytes must be a non-negative integer")
+		}
+		opts.limitBytes = int(limitBytes)
+	}
+
+	return opts, nil
+}
+
+// taskRunLogs fetches the logs of the step (and optionally sidecar) containers of the TaskRun's pod.
+// Errors fetching a container logs are reported inline so that the logs of the other containers are kept.
+func taskRunLogs(ctx context.Context, tr *v1.TaskRun, opts logOptions) string {
+	var b strings.Builder
+
+	if tr.Status.PodName == "" {
+		fmt.Fprintf(&b, "=== TaskRun %s: no pod has been created yet ===\n", tr.Name)
+		return b.String()
+	}
+
+	type container struct {
+		title string
+		name  string
+	}
+	var containers []container
+	for _, step := range tr.Status.Steps {
+		if len(opts.steps) > 0 && !slices.Contains(opts.steps, step.Name) {
+			continue
+		}
+		title := fmt.Sprintf("step %s", step.Name)
+		if step.Terminated != nil {
+			title = fmt.Sprintf("%s (exit code %d, %s)", title, step.Terminated.ExitCode, step.Terminated.Reason)
+		}
+		containers = append(containers, container{title: title, name: step.Container})
+	}
+	for _, sidecar := range tr.Status.Sidecars {
+		if !opts.sidecars && !slices.Contains(opts.steps, sidecar.Name) {
+			continue
+		}
+		containers = append(containers, container{title: fmt.Sprintf("sidecar %s", sidecar.Name), name: sidecar.Container})
+	}
+
+	for _, c := range containers {
+		fmt.Fprintf(&b, "=== TaskRun %s / %s ===\n", tr.Name, c.title)
+		logs, err := containerLogs(ctx, tr.Namespace, tr.Status.PodName, c.name, opts)
+		if err != nil {
+			fmt.Fprintf(&b, "failed to get logs of container %s: %v\n", c.name, err)
+			continue
+		}
+		b.WriteString(logs)
+		if logs != "" && !strings.HasSuffix(logs, "\n") {
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+// pipelineRunLogs aggregates the logs of the child TaskRuns of a PipelineRun, in DAG order.
+func pipelineRunLogs(ctx context.Context, pr *v1.PipelineRun, opts logOptions) (string, error) {
+	selector := labels.SelectorFromSet(labels.Set{pipeline.PipelineRunLabelKey: pr.Name})
+	trs, err := taskruninformer.Get(ctx).Lister().TaskRuns(pr.Namespace).List(selector)
+	if err != nil {
+		return "", err
+	}
+	sortTaskRunsInDAGOrder(pr, trs)
+
+	var b strings.Builder
+	for _, tr := range trs {
+		fmt.Fprintf(&b, "##### Task %s #####\n", tr.Labels[pipeline.PipelineTaskLabelKey])
+		b.WriteString(taskRunLogs(ctx, tr, opts))
+	}
+	if len(trs) == 0 {
+		fmt.Fprintf(&b, "PipelineRun %s has no TaskRuns yet\n", pr.Name)
+	}
+	return b.String(), nil
+}
+
+// sortTaskRunsInDAGOrder sorts the TaskRuns of a PipelineRun following the order in which their
+// pipeline tasks can run: dependencies first, then declaration order, then finally tasks.
+func sortTaskRunsInDAGOrder(pr *v1.PipelineRun, trs []*v1.TaskRun) {
+	order := map[string]int{}
+	if pr.Status.PipelineSpec != nil {
+		for i, name := range pipelineTaskOrder(pr.Status.PipelineSpec) {
+			order[name] = i
+		}
+	}
+
+	sort.SliceStable(trs, func(i, j int) bool {
+		oi, iok := order[trs[i].Labels[pipeline.PipelineTaskLabelKey]]
+		oj, jok := order[trs[j].Labels[pipeline.PipelineTaskLabelKey]]
+		if iok != jok {
+			return iok
+		}
+		if oi != oj {
+			return oi < oj
+		}
+		if !trs[i].CreationTimestamp.Equal(&trs[j].CreationTimestamp) {
+			return trs[i].CreationTimestamp.Before(&trs[j].CreationTimestamp)
+		}
+		return trs[i].Name < trs[j].Name
+	})
+}
+
+// pipelineTaskOrder returns the names of the tasks of a Pipeline in topological order,
+// breaking ties with the declaration order, followed by the finally tasks.
+func pipelineTaskOrder(spec *v1.PipelineSpec) []string {
+	deps := v1.PipelineTaskList(spec.Tasks).Deps()
+
+	var order []string
+	visited := map[string]bool{}
+	for len(order) < len(spec.Tasks) {
+		progressed := false
+		for _, task := range spec.Tasks {
+			if visited[task.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range deps[task.Name] {
+				if !visited[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				visited[task.Name] = true
+				order = append(order, task.Name)
+				progressed = true
+			}
+		}
+		if !progressed {
+			// Invalid graph, keep the remaining tasks in declaration order
+			for _, task := range spec.Tasks {
+				if !visited[task.Name] {
+					visited[task.Name] = true
+					order = append(order, task.Name)
+				}
+			}
+		}
+	}
+
+	for _, task := range spec.Finally {
+		order = append(order, task.Name)
+	}
+	return order
+}
+
+// containerLogs fetches the logs of a container, keeping at most limitBytes from the end of the logs.
+func containerLogs(ctx context.Context, namespace, podName, containerName string, opts logOptions) (string, error) {
+	stream, err := kubeclient.Get(ctx).CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: containerName,
+		TailLines: opts.tailLines,
+		SinceTime: opts.sinceTime,
+	}).Stream(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	return readLogTail(stream, opts.limitBytes)
+}
+
+// readLogTail reads logs until the end, keeping at most limitBytes (maxLogReadBytes if limitBytes
+// is 0) from the end of the logs.
+func readLogTail(r io.Reader, limitBytes int) (string, error) {
+	if limitBytes <= 0 || limitBytes > maxLogReadBytes {
+		limitBytes = maxLogReadBytes
+	}
+	tail := &tailBuffer{size: limitBytes}
+	if _, err := io.Copy(tail, r); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	data := tail.bytes()
+	if truncated := tail.total - int64(len(data)); truncated > 0 {
+		return fmt.Sprintf("[... %d bytes truncated ...]\n%s", truncated, data), nil
+	}
+	return string(data), nil
+}
+
+// tailBuffer is a ring buffer keeping the last size bytes written to it.
+type tailBuffer struct {
+	size int
+	buf  []byte
+	// start is the position of the oldest byte in buf once it is full.
+	start int
+	// total is the number of bytes written.
+	total int64
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	t.total += int64(n)
+	if len(t.buf) < t.size {
+		fill := min(t.size-len(t.buf), len(p))
+		t.buf = append(t.buf, p[:fill]...)
+		p = p[fill:]
+	}
+	if len(p) >= t.size {
+		copy(t.buf, p[len(p)-t.size:])
+		t.start = 0
+	} else if len(p) > 0 {
+		copied := copy(t.buf[t.start:], p)
+		copy(t.buf, p[copied:])
+		t.start = (t.start + len(p)) % t.size
+	}
+	return n, nil
+}
+
+// bytes returns the bytes kept, oldest first.
+func (t *tailBuffer) bytes() []byte {
+	return append(t.buf[t.start:len(t.buf):len(t.buf)], t.buf[:t.start]...)
+}
+
+func GetTaskRunLogsResourceContent(ctx context.Context) (mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc) {
+	return mcp.NewResourceTemplate(
+		"tekton://taskrun/{namespace}/{name}/logs",
+		"TaskRun logs",
+		mcp.WithTemplateDescription("Logs of the steps of a TaskRun"),
+		mcp.WithTemplateMIMEType("text/plain"),
+	), TaskRunLogsContentHandler(ctx)
+}
+
+func TaskRunLogsContentHandler(ctx context.Context) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		ns, ok := request.Params.Arguments["namespace"].([]string)
+		if !ok || len(ns) == 0 {
+			return nil, errors.New("namespace is required")
+		}
+		namespace := ns[0]
+		if err := ConfigFromContext(ctx).CheckNamespace(namespace); err != nil {
+			return nil, err
+		}
+
+		n, ok := request.Params.Arguments["name"].([]string)
+		if !ok || len(n) == 0 {
+			return nil, errors.New("name is required")
+		}
+		name := n[0]
+
+		if err := authorize(ctx, "get", "tekton.dev", "taskruns", "", namespace, name); err != nil {
+			return nil, err
+		}
+		if err := authorize(ctx, "get", "", "pods", "log", namespace, ""); err != nil {
+			return nil, err
+		}
+
+		opts := logOptions{limitBytes: defaultLogLimitBytes}
+		tr, err := taskruninformer.Get(ctx).Lister().TaskRuns(namespace).Get(name)
+		if apierrors.IsNotFound(err) {
+			k, _ := listKindOf("taskrun")
+			logs, found, resultsErr := archivedLogs(ctx, k, namespace, name, opts)
+			if resultsErr != nil {
+				return nil, fmt.Errorf("failed to get logs of TaskRun %s/%s from Tekton Results: %w", namespace, name, resultsErr)
+			}
+			if found {
+				return []mcp.ResourceContents{mcp.TextResourceContents{
+					URI:      request.Params.URI,
+					MIMEType: "text/plain",
+					Text:     logs,
+				}}, nil
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TaskRun %s/%s: %w", namespace, name, err)
+		}
+
+		contents := mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "text/plain",
+			Text:     taskRunLogs(ctx, tr, opts),
+		}
+
+		return []mcp.ResourceContents{contents}, nil
+	}
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestReadLogTail(t *testing.T) {
+	logs := strings.Repeat("0123456789", 10)
+
+	tests := []struct {
+		name       string
+		limitBytes int
+		chunk      int
+		want       string
+	}{
+		{name: "shorter than the limit", limitBytes: 200, chunk: 7, want: logs},
+		{name: "as long as the limit", limitBytes: 100, chunk: 100, want: logs},
+		{name: "no limit", chunk: 3, want: logs},
+		{name: "small writes", limitBytes: 15, chunk: 1, want: "[... 85 bytes truncated ...]\n567890123456789"},
+		{name: "writes not aligned with the limit", limitBytes: 15, chunk: 7, want: "[... 85 bytes truncated ...]\n567890123456789"},
+		{name: "writes longer than the limit", limitBytes: 15, chunk: 40, want: "[... 85 bytes truncated ...]\n567890123456789"},
+		{name: "single write", limitBytes: 4, chunk: 100, want: "[... 96 bytes truncated ...]\n6789"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &chunkReader{data: logs, chunk: test.chunk}
+			got, err := readLogTail(r, test.limitBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// chunkReader returns its data in reads of at most chunk bytes.
+type chunkReader struct {
+	data  string
+	chunk int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, io.EOF
+	}
+	n := copy(p[:min(len(p), r.chunk)], r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestLogParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]any
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "valid", args: map[string]any{"tail-lines": float64(10), "limit-bytes": float64(1024)}},
+		{name: "negative tail-lines", args: map[string]any{"tail-lines": float64(-1)}, wantErr: "parameter tail-lines must be a non-negative integer"},
+		{name: "fractional tail-lines", args: map[string]any{"tail-lines": 1.5}, wantErr: "parameter tail-lines must be a non-negative integer"},
+		{name: "negative limit-bytes", args: map[string]any{"limit-bytes": float64(-10)}, wantErr: "parameter limit-bytes must be a non-negative integer"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = test.args
+			_, err := logParams(request)
+			if test.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.wantErr != "" && (err == nil || err.Error() != test.wantErr) {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// namespacesURI is the URI of the resource summarizing every namespace containing Tekton objects.
+const namespacesURI = "tekton://namespaces"
+
+// recentRunsWindow is how long ago runs were created to be counted as recent in the namespace summaries.
+const recentRunsWindow = 24 * time.Hour
+
+// namespaceSummary counts the Tekton objects of a namespace.
+type namespaceSummary struct {
+	Namespace string `json:"namespace"`
+	URI       string `json:"uri"`
+	// Counts are the numbers of objects of each listed kind, by resource (e.g. pipelines).
+	Counts map[string]int `json:"counts"`
+	// RecentRuns are the numbers of runs created since RecentSince, by resource and status.
+	RecentRuns  map[string]map[string]int `json:"recentRuns"`
+	RecentSince metav1.Time               `json:"recentSince"`
+	// Unauthorized are the resources the caller is not allowed to list, they are not counted.
+	Unauthorized []string `json:"unauthorized,omitempty"`
+}
+
+// TektonNamespaceURI returns the tekton:// URI of the summary of a namespace.
+func TektonNamespaceURI(namespace string) string {
+	return fmt.Sprintf("tekton://namespace/%s", namespace)
+}
+
+func GetNamespacesResourceContent(ctx context.Context) (mcp.Resource, server.ResourceHandlerFunc) {
+	return mcp.NewResource(
+		namespacesURI,
+		"Namespaces",
+		mcp.WithResourceDescription("The namespaces containing Tekton objects, with the number of Pipelines, Tasks, StepActions and runs of each, and the recent runs by status"),
+		mcp.WithMIMEType("application/json"),
+	), NamespacesContentHandler(ctx)
+}
+
+func GetNamespaceResourceContent(ctx context.Context) (mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc) {
+	return mcp.NewResourceTemplate(
+		"tekton://namespace/{namespace}",
+		"Namespace",
+		mcp.WithTemplateDescription("The number of Pipelines, Tasks, StepActions and runs of a namespace, and its recent runs by status"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), NamespaceContentHandler(ctx)
+}
+
+func NamespacesContentHandler(ctx context.Context) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		summaries := namespaceSummaries(ctx, "")
+		list := make([]namespaceSummary, 0, len(summaries))
+		for _, summary := range summaries {
+			list = append(list, summary)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Namespace < list[j].Namespace
+		})
+		return namespaceContents(request.Params.URI, list)
+	}
+}
+
+func NamespaceContentHandler(ctx context.Context) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		ns, ok := request.Params.Arguments["namespace"].([]string)
+		if !ok || len(ns) == 0 || ns[0] == "" {
+			return nil, errors.New("namespace is required")
+		}
+		namespace := ns[0]
+		if err := ConfigFromContext(ctx).CheckNamespace(namespace); err != nil {
+			return nil, err
+		}
+
+		summary, ok := namespaceSummaries(ctx, namespace)[namespace]
+		if !ok {
+			summary = newNamespaceSummary(namespace, time.Now().Add(-recentRunsWindow), listAuthorizer(ctx))
+		}
+		return namespaceContents(request.Params.URI, summary)
+	}
+}
+
+func namespaceContents(uri string, content any) ([]mcp.ResourceContents, error) {
+	jsonData, err := json.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     string(jsonData),
+	}}, nil
+}
+
+// newNamespaceSummary returns a summary counting no objects of the kinds the caller can list, and
+// naming the other kinds as unauthorized.
+func newNamespaceSummary(namespace string, since time.Time, canList func(k listKind, namespace string) bool) namespaceSummary {
+	s := namespaceSummary{
+		Namespace:   namespace,
+		URI:         TektonNamespaceURI(namespace),
+		Counts:      map[string]int{},
+		RecentRuns:  map[string]map[string]int{},
+		RecentSince: metav1.NewTime(since),
+	}
+	for _, k := range listKinds {
+		if !canList(k, namespace) {
+			s.Unauthorized = append(s.Unauthorized, k.resource())
+			continue
+		}
+		s.Counts[k.resource()] = 0
+		if k.spec.runRef != "" {
+			s.RecentRuns[k.resource()] = map[string]int{}
+		}
+	}
+	return s
+}
+
+// namespaceSummaries counts the cached objects of a namespace, or of every allowed namespace if
+// namespace is empty. The kinds the caller is not allowed to list are not counted, and reported as
+// unauthorized.
+func namespaceSummaries(ctx context.Context, namespace string) map[string]namespaceSummary {
+	cfg := ConfigFromContext(ctx)
+	canList := listAuthorizer(ctx)
+	since := time.Now().Add(-recentRunsWindow)
+
+	summaries := map[string]namespaceSummary{}
+	for _, k := range listKinds {
+		objs, err := listCached(k.informer(ctx), namespace, labels.Everything())
+		if err != nil {
+			slog.Error(fmt.Sprintf("failed to list %ss: %v", k.kind, err))
+			continue
+		}
+		for _, obj := range objs {
+			ns := obj.GetNamespace()
+			if !cfg.Namespaces.Allowed(ns) || !canList(k, ns) {
+				continue
+			}
+			summary, ok := summaries[ns]
+			if !ok {
+				summary = newNamespaceSummary(ns, since, canList)
+				summaries[ns] = summary
+			}
+			summary.Counts[k.resource()]++
+			if run, ok := runFieldsOf(obj); ok && obj.GetCreationTimestamp().After(since) {
+				summary.RecentRuns[k.resource()][run.state()]++
+			}
+		}
+	}
+	return summaries
+}
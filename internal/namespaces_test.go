@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewNamespaceSummary(t *testing.T) {
+	denied := map[string]bool{"pipelines": true, "taskruns": true}
+	canList := func(k listKind, namespace string) bool {
+		return namespace == "default" && !denied[k.resource()]
+	}
+
+	s := newNamespaceSummary("default", time.Now(), canList)
+	if !reflect.DeepEqual(s.Unauthorized, []string{"taskruns", "pipelines"}) {
+		t.Errorf("got unauthorized %v, want [taskruns pipelines]", s.Unauthorized)
+	}
+	for _, resource := range []string{"pipelines", "taskruns"} {
+		if _, ok := s.Counts[resource]; ok {
+			t.Errorf("%s are counted", resource)
+		}
+		if _, ok := s.RecentRuns[resource]; ok {
+			t.Errorf("recent %s are counted", resource)
+		}
+	}
+	for _, resource := range []string{"pipelineruns", "tasks"} {
+		if count, ok := s.Counts[resource]; !ok || count != 0 {
+			t.Errorf("got %d %s, want 0", count, resource)
+		}
+	}
+	if _, ok := s.RecentRuns["pipelineruns"]; !ok {
+		t.Error("recent pipelineruns are not counted")
+	}
+
+	if s := newNamespaceSummary("other", time.Now(), canList); len(s.Counts) != 0 || len(s.Unauthorized) != len(listKinds) {
+		t.Errorf("got counts %v and unauthorized %v in a namespace the caller cannot list", s.Counts, s.Unauthorized)
+	}
+}
@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// outputFormat is how the list tools render each object.
+type outputFormat struct {
+	// mode is one of summary, names, full, yaml, jsonpath or fields.
+	mode string
+	// jsonPath is the template of the jsonpath mode.
+	jsonPath *jsonpath.JSONPath
+	// fields are the dotted paths kept by the fields mode.
+	fields [][]string
+}
+
+// outputToolOption returns the output argument of a list tool.
+func outputToolOption(defaultOutput string) mcp.ToolOption {
+	return mcp.WithString("output",
+		mcp.Description(fmt.Sprintf("How to render each object: summary (name, namespace, status, times and reference), names (namespace/name), "+
+			"full (the whole object), yaml (the whole objects as YAML documents, like kubectl get -o yaml), jsonpath=<template> (e.g. jsonpath={.status.conditions[0].reason}) "+
+			"or fields=<paths> (a projection of comma-separated dotted paths, e.g. fields=metadata.name,spec.params), defaults to %s", defaultOutput)),
+	)
+}
+
+// outputParams reads the output argument of a list tool.
+func outputParams(request mcp.CallToolRequest, defaultOutput string) (outputFormat, error) {
+	output, err := OptionalParam[string](request, "output")
+	if err != nil {
+		return outputFormat{}, err
+	}
+	if output == "" {
+		output = defaultOutput
+	}
+
+	mode, arg, _ := strings.Cut(output, "=")
+	format := outputFormat{mode: mode}
+	switch mode {
+	case "summary", "names", "full", "yaml":
+		if arg != "" {
+			return format, fmt.Errorf("output %s doesn't take a value", mode)
+		}
+	case "jsonpath":
+		if !strings.Contains(arg, "{") {
+			arg = fmt.Sprintf("{%s}", arg)
+		}
+		format.jsonPath = jsonpath.New("output").AllowMissingKeys(true)
+		if err := format.jsonPath.Parse(arg); err != nil {
+			return format, fmt.Errorf("output jsonpath is not a valid template: %w", err)
+		}
+	case "fields":
+		for _, field := range strings.Split(arg, ",") {
+			field = strings.Trim(strings.TrimSpace(field), ".")
+			if field == "" {
+				continue
+			}
+			format.fields = append(format.fields, strings.Split(field, "."))
+		}
+		if len(format.fields) == 0 {
+			return format, fmt.Errorf("output fields requires at least one path, e.g. fields=metadata.name")
+		}
+	default:
+		return format, fmt.Errorf("output must be summary, names, full, yaml, jsonpath=<template> or fields=<paths>, not %q", output)
+	}
+	return format, nil
+}
+
+// formatObjects renders the objects in the given output format. The summaries of the objects that
+// have a record were read from Tekton Results.
+func formatObjects[T metav1.Object](objs []T, format outputFormat, records map[types.UID]string) (any, error) {
+	switch format.mode {
+	case "full":
+		return objs, nil
+	case "names":
+		names := make([]string, 0, len(objs))
+		for _, obj := range objs {
+			names = append(names, fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName()))
+		}
+		return names, nil
+	case "summary":
+		summaries := make([]any, 0, len(objs))
+		for _, obj := range objs {
+			summary := objectSummary(obj)
+			if s, ok := summary.(runSummary); ok && records[obj.GetUID()] != "" {
+				s.Source = "results"
+				summary = s
+			}
+			summaries = append(summaries, summary)
+		}
+		return summaries, nil
+	}
+
+	rendered := make([]any, 0, len(objs))
+	for _, obj := range objs {
+		content, err := toUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		if format.jsonPath != nil {
+			values, err := jsonPathValues(format.jsonPath, content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate jsonpath on %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			}
+			rendered = append(rendered, map[string]any{
+				"namespace": obj.GetNamespace(),
+				"name":      obj.GetName(),
+				"value":     values,
+			})
+			continue
+		}
+		projection := map[string]any{}
+		for _, field := range format.fields {
+			if value, found, _ := unstructured.NestedFieldNoCopy(content, field...); found {
+				_ = unstructured.SetNestedField(projection, value, field...)
+			}
+		}
+		rendered = append(rendered, projection)
+	}
+	return rendered, nil
+}
+
+func toUnstructured(obj any) (map[string]any, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
+	}
+	content := map[string]any{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resource from JSON: %w", err)
+	}
+	return content, nil
+}
+
+// jsonPathValues returns the value matched by the template, or the list of values if it matched several.
+func jsonPathValues(j *jsonpath.JSONPath, content map[string]any) (any, error) {
+	results, err := j.FindResults(content)
+	if err != nil {
+		return nil, err
+	}
+	var values []any
+	for _, result := range results {
+		for _, value := range result {
+			values = append(values, value.Interface())
+		}
+	}
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case 1:
+		return values[0], nil
+	}
+	return values, nil
+}
+
+// runSummary is the compact description of a PipelineRun, TaskRun or CustomRun.
+type runSummary struct {
+	Name           string       `json:"name"`
+	Namespace      string       `json:"namespace"`
+	Pipeline       string       `json:"pipeline,omitempty"`
+	Task           string       `json:"task,omitempty"`
+	CustomTask     string       `json:"customTask,omitempty"`
+	PipelineRun    string       `json:"pipelineRun,omitempty"`
+	Status         string       `json:"status,omitempty"`
+	Reason         string       `json:"reason,omitempty"`
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	Duration       string       `json:"duration,omitempty"`
+	// Source is results for the runs that are no longer in the cluster, read from Tekton Results.
+	Source string `json:"source,omitempty"`
+}
+
+// definitionSummary is the compact description of a Pipeline, Task or StepAction.
+type definitionSummary struct {
+	Name              string      `json:"name"`
+	Namespace         string      `json:"namespace"`
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+	Description       string      `json:"description,omitempty"`
+}
+
+// objectSummary returns the compact description of an object.
+func objectSummary(obj metav1.Object) any {
+	switch o := obj.(type) {
+	case *v1.PipelineRun:
+		status, reason, _ := succeededCondition(o.Status.Status)
+		return runSummary{
+			Name:           o.Name,
+			Namespace:      o.Namespace,
+			Pipeline:       pipelineRefName(o.Spec.PipelineRef, o.Labels[pipeline.PipelineLabelKey]),
+			Status:         status,
+			Reason:         reason,
+			StartTime:      o.Status.StartTime,
+			CompletionTime: o.Status.CompletionTime,
+			Duration:       runDuration(o.Status.StartTime, o.Status.CompletionTime),
+		}
+	case *v1.TaskRun:
+		status, reason, _ := succeededCondition(o.Status.Status)
+		return runSummary{
+			Name:           o.Name,
+			Namespace:      o.Namespace,
+			Task:           taskRefName(o.Spec.TaskRef, o.Labels[pipeline.TaskLabelKey]),
+			PipelineRun:    o.Labels[pipeline.PipelineRunLabelKey],
+			Status:         status,
+			Reason:         reason,
+			StartTime:      o.Status.StartTime,
+			CompletionTime: o.Status.CompletionTime,
+			Duration:       runDuration(o.Status.StartTime, o.Status.CompletionTime),
+		}
+	case *v1beta1.CustomRun:
+		status, reason, _ := succeededCondition(o.Status.Status)
+		return runSummary{
+			Name:           o.Name,
+			Namespace:      o.Namespace,
+			CustomTask:     customTaskKind(o.Spec),
+			PipelineRun:    o.Labels[pipeline.PipelineRunLabelKey],
+			Status:         status,
+			Reason:         reason,
+			StartTime:      o.Status.StartTime,
+			CompletionTime: o.Status.CompletionTime,
+			Duration:       runDuration(o.Status.StartTime, o.Status.CompletionTime),
+		}
+	}
+
+	s := definitionSummary{
+		Name:              obj.GetName(),
+		Namespace:         obj.GetNamespace(),
+		CreationTimestamp: obj.GetCreationTimestamp(),
+	}
+	switch o := obj.(type) {
+	case *v1.Pipeline:
+		s.Description = o.Spec.Description
+	case *v1.Task:
+		s.Description = o.Spec.Description
+	case *v1beta1.StepAction:
+		s.Description = o.Spec.Description
+	}
+	return s
+}
+
+// pipelineRefName returns the name of the Pipeline referenced by a run, its resolver for remote
+// Pipelines, falling back to the label set by the controller.
+func pipelineRefName(ref *v1.PipelineRef, label string) string {
+	switch {
+	case ref != nil && ref.Name != "":
+		return ref.Name
+	case label != "":
+		return label
+	case ref != nil && ref.Resolver != "":
+		return fmt.Sprintf("%s resolver", ref.Resolver)
+	}
+	return ""
+}
+
+// taskRefName returns the name of the Task referenced by a run, its resolver for remote Tasks,
+// falling back to the label set by the controller.
+func taskRefName(ref *v1.TaskRef, label string) string {
+	switch {
+	case ref != nil && ref.Name != "":
+		return ref.Name
+	case label != "":
+		return label
+	case ref != nil && ref.Resolver != "":
+		return fmt.Sprintf("%s resolver", ref.Resolver)
+	}
+	return ""
+}
+
+// customTaskKind returns the kind of the custom task run by a CustomRun, referenced or embedded.
+func customTaskKind(spec v1beta1.CustomRunSpec) string {
+	switch {
+	case spec.CustomRef != nil:
+		return string(spec.CustomRef.Kind)
+	case spec.CustomSpec != nil:
+		return spec.CustomSpec.Kind
+	}
+	return ""
+}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -25,3 +27,29 @@ func OptionalParam[T any](r mcp.CallToolRequest, p string) (T, error) {
 
 	return r.Params.Arguments[p].(T), nil
 }
+
+// DecodeParam is a helper function that can be used to decode a structured parameter from the request
+// into out (a Kubernetes or Tekton type, for example).
+// It does the following checks:
+// 1. Checks if the parameter is present in the request, if not, it returns false and leaves out untouched
+// 2. If it is present, it round-trips it through JSON into out, rejecting unknown fields
+func DecodeParam(r mcp.CallToolRequest, p string, out any) (bool, error) {
+	// Check if the parameter is present in the request
+	raw, ok := r.Params.Arguments[p]
+	if !ok || raw == nil {
+		return false, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return false, fmt.Errorf("parameter %s cannot be encoded: %w", p, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(out); err != nil {
+		return false, fmt.Errorf("parameter %s is not a valid %T: %w", p, out, err)
+	}
+
+	return true, nil
+}
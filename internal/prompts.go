@@ -2,21 +2,322 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
+	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 )
 
+// failedStepLogLines is the number of lines of logs included for each failed step.
+const failedStepLogLines = 50
+
+// failedStepLogBytes is the maximum number of bytes of logs included for each failed step.
+const failedStepLogBytes = 4 * 1024
+
 func handlerExplainPipelineError(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
-	return nil, errors.New("not Implemented yet")
+	namespace, err := ConfigFromContext(ctx).Namespace(request.Params.Arguments["namespace"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, "list", "tekton.dev", "pipelineruns", "", namespace, ""); err != nil {
+		return nil, err
+	}
+
+	pr, err := failedPipelineRun(ctx, namespace, request.Params.Arguments["pipelinerun"], request.Params.Arguments["pipeline"])
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "The PipelineRun %s/%s", pr.Namespace, pr.Name)
+	if pr.Spec.PipelineRef != nil && pr.Spec.PipelineRef.Name != "" {
+		fmt.Fprintf(&b, " of the Pipeline %s", pr.Spec.PipelineRef.Name)
+	}
+
+	// A named PipelineRun may not have failed
+	status, reason, message := succeededCondition(pr.Status.Status)
+	if status != string(corev1.ConditionFalse) {
+		state := "is still running"
+		if status == string(corev1.ConditionTrue) {
+			state = "succeeded"
+		}
+		fmt.Fprintf(&b, " %s, there is no failure to explain. Tell the user that it %s.\n\n", state, state)
+		fmt.Fprintf(&b, "PipelineRun condition: status=%s reason=%s\nmessage: %s\n", status, reason, message)
+		return mcp.NewGetPromptResult(
+			fmt.Sprintf("PipelineRun %s/%s %s", pr.Namespace, pr.Name, state),
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(b.String()))},
+		), nil
+	}
+
+	b.WriteString(" did not succeed. Explain why it failed, point at the failing task and step, and suggest how to fix it.\n\n")
+	fmt.Fprintf(&b, "PipelineRun condition: status=%s reason=%s\nmessage: %s\n", status, reason, message)
+
+	// Only include the sections the caller is allowed to see
+	var omitted []string
+	allowed := func(section, verb, group, resource, subresource string) bool {
+		if err := authorize(ctx, verb, group, resource, subresource, namespace, ""); err != nil {
+			omitted = append(omitted, section)
+			return false
+		}
+		return true
+	}
+	withTaskRuns := allowed("failed TaskRuns", "get", "tekton.dev", "taskruns", "")
+	withCustomRuns := allowed("failed CustomRuns", "get", "tekton.dev", "customruns", "")
+	withLogs := withTaskRuns && allowed("step logs", "get", "", "pods", "log")
+	withEvents := allowed("Kubernetes events", "list", "", "events", "")
+
+	var embedded []mcp.ResourceContents
+	if contents, ok := cachedResourceContents(ctx, "pipelinerun", pr.Namespace, pr.Name); ok {
+		embedded = append(embedded, contents)
+	}
+
+	var events []string
+	if withEvents {
+		events = objectEvents(ctx, pr.Namespace, pr.Name)
+	}
+
+	var trs []*v1.TaskRun
+	if withTaskRuns {
+		trs = failedTaskRuns(ctx, pr)
+	}
+	for _, tr := range trs {
+		_, trReason, trMessage := succeededCondition(tr.Status.Status)
+		fmt.Fprintf(&b, "\nFailed TaskRun %s (pipeline task %s): reason=%s\nmessage: %s\n", tr.Name, tr.Labels[pipeline.PipelineTaskLabelKey], trReason, trMessage)
+
+		var failedSteps []string
+		for _, step := range tr.Status.Steps {
+			if step.Terminated == nil || step.Terminated.ExitCode == 0 {
+				continue
+			}
+			failedSteps = append(failedSteps, step.Name)
+			s := stepStateOutcome(step)
+			exitCode := "unknown"
+			if s.ExitCode != nil {
+				exitCode = fmt.Sprint(*s.ExitCode)
+			}
+			fmt.Fprintf(&b, "- step %s terminated with exit code %s, reason %s", s.Name, exitCode, s.Reason)
+			if step.Terminated.Message != "" {
+				fmt.Fprintf(&b, ", message: %s", step.Terminated.Message)
+			}
+			b.WriteString("\n")
+		}
+
+		if contents, ok := cachedResourceContents(ctx, "taskrun", tr.Namespace, tr.Name); ok {
+			embedded = append(embedded, contents)
+		}
+
+		if withLogs && len(failedSteps) > 0 {
+			lines := int64(failedStepLogLines)
+			logs := taskRunLogs(ctx, tr, logOptions{steps: failedSteps, tailLines: &lines, limitBytes: failedStepLogBytes})
+			embedded = append(embedded, mcp.TextResourceContents{
+				URI:      fmt.Sprintf("%s/logs", TektonResourceURI("taskrun", tr.Namespace, tr.Name)),
+				MIMEType: "text/plain",
+				Text:     logs,
+			})
+		}
+
+		if withEvents {
+			events = append(events, objectEvents(ctx, tr.Namespace, tr.Name)...)
+			if tr.Status.PodName != "" {
+				events = append(events, objectEvents(ctx, tr.Namespace, tr.Status.PodName)...)
+			}
+		}
+	}
+
+	var crs []*v1beta1.CustomRun
+	if withCustomRuns {
+		crs = failedCustomRuns(ctx, pr)
+	}
+	for _, cr := range crs {
+		_, crReason, crMessage := succeededCondition(cr.Status.Status)
+		fmt.Fprintf(&b, "\nFailed CustomRun %s (pipeline task %s, custom task %s): reason=%s\nmessage: %s\n", cr.Name, cr.Labels[pipeline.PipelineTaskLabelKey], customTaskKind(cr.Spec), crReason, crMessage)
+
+		if jsonData, err := json.Marshal(cr); err == nil {
+			embedded = append(embedded, jsonResourceContents("customrun", cr.Namespace, cr.Name, jsonData))
+		}
+		if withEvents {
+			events = append(events, objectEvents(ctx, cr.Namespace, cr.Name)...)
+		}
+	}
+
+	if len(events) > 0 {
+		b.WriteString("\nRelated Kubernetes events:\n")
+		for _, e := range events {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+	}
+
+	if len(omitted) > 0 {
+		fmt.Fprintf(&b, "\nNot included because the caller is not allowed to read them: %s.\n", strings.Join(omitted, ", "))
+	}
+
+	messages := []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(b.String())),
+	}
+	for _, resource := range embedded {
+		messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(resource)))
+	}
+
+	return mcp.NewGetPromptResult(
+		fmt.Sprintf("Explain the failure of PipelineRun %s/%s", pr.Namespace, pr.Name),
+		messages,
+	), nil
+}
+
+// failedPipelineRun returns the named PipelineRun, or the most recent failed PipelineRun of the named Pipeline.
+func failedPipelineRun(ctx context.Context, namespace, pipelineRunName, pipelineName string) (*v1.PipelineRun, error) {
+	lister := pipelineruninformer.Get(ctx).Lister().PipelineRuns(namespace)
+
+	if pipelineRunName != "" {
+		pr, err := lister.Get(pipelineRunName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get PipelineRun %s/%s: %w", namespace, pipelineRunName, err)
+		}
+		return pr, nil
+	}
+
+	if pipelineName == "" {
+		return nil, errors.New("either pipeline or pipelinerun is required")
+	}
+
+	prs, err := lister.List(labels.SelectorFromSet(labels.Set{pipeline.PipelineLabelKey: pipelineName}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PipelineRuns of Pipeline %s/%s: %w", namespace, pipelineName, err)
+	}
+
+	var latest *v1.PipelineRun
+	for _, pr := range prs {
+		if status, _, _ := succeededCondition(pr.Status.Status); status != string(corev1.ConditionFalse) {
+			continue
+		}
+		if latest == nil || finishedAt(pr).After(finishedAt(latest).Time) {
+			latest = pr
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no failed PipelineRun found for Pipeline %s/%s", namespace, pipelineName)
+	}
+	return latest, nil
+}
+
+func finishedAt(pr *v1.PipelineRun) metav1.Time {
+	if pr.Status.CompletionTime != nil {
+		return *pr.Status.CompletionTime
+	}
+	return pr.CreationTimestamp
+}
+
+// failedTaskRuns returns the child TaskRuns of a PipelineRun that did not succeed.
+func failedTaskRuns(ctx context.Context, pr *v1.PipelineRun) []*v1.TaskRun {
+	selector := labels.SelectorFromSet(labels.Set{pipeline.PipelineRunLabelKey: pr.Name})
+	trs, err := taskruninformer.Get(ctx).Lister().TaskRuns(pr.Namespace).List(selector)
+	if err != nil {
+		return nil
+	}
+	sortTaskRunsInDAGOrder(pr, trs)
+
+	var failed []*v1.TaskRun
+	for _, tr := range trs {
+		if status, _, _ := succeededCondition(tr.Status.Status); status == string(corev1.ConditionFalse) {
+			failed = append(failed, tr)
+		}
+	}
+	return failed
+}
+
+// failedCustomRuns returns the child CustomRuns of a PipelineRun that did not succeed.
+func failedCustomRuns(ctx context.Context, pr *v1.PipelineRun) []*v1beta1.CustomRun {
+	selector := labels.SelectorFromSet(labels.Set{pipeline.PipelineRunLabelKey: pr.Name})
+	crs, err := customruninformer.Get(ctx).Lister().CustomRuns(pr.Namespace).List(selector)
+	if err != nil {
+		return nil
+	}
+	sort.Slice(crs, func(i, j int) bool {
+		return crs[i].Name < crs[j].Name
+	})
+
+	var failed []*v1beta1.CustomRun
+	for _, cr := range crs {
+		if status, _, _ := succeededCondition(cr.Status.Status); status == string(corev1.ConditionFalse) {
+			failed = append(failed, cr)
+		}
+	}
+	return failed
+}
+
+// objectEvents returns a one-line description of the Kubernetes events involving the named object.
+func objectEvents(ctx context.Context, namespace, name string) []string {
+	events, err := kubeclient.Get(ctx).CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.name", name).String(),
+	})
+	if err != nil {
+		return nil
+	}
+
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+	})
+
+	var descriptions []string
+	for _, e := range events.Items {
+		descriptions = append(descriptions, fmt.Sprintf("%s %s/%s: %s %s (x%d)", e.Type, e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Reason, e.Message, max(e.Count, 1)))
+	}
+	return descriptions
+}
+
+// cachedResourceContents returns the JSON resource of an object of the informer cache, false if it
+// is not cached.
+func cachedResourceContents(ctx context.Context, resourceType, namespace, name string) (mcp.TextResourceContents, bool) {
+	k, ok := listKindOf(resourceType)
+	if !ok {
+		return mcp.TextResourceContents{}, false
+	}
+	obj, err := k.getCached(ctx, namespace, name)
+	if err != nil {
+		return mcp.TextResourceContents{}, false
+	}
+	jsonData, err := objectJSON(obj)
+	if err != nil {
+		return mcp.TextResourceContents{}, false
+	}
+	return jsonResourceContents(resourceType, namespace, name, jsonData), true
+}
+
+func jsonResourceContents(resourceType, namespace, name string, jsonData []byte) mcp.TextResourceContents {
+	return mcp.TextResourceContents{
+		URI:      TektonResourceURI(resourceType, namespace, name),
+		MIMEType: fmt.Sprintf("application/json;type=%s", resourceType),
+		Text:     string(jsonData),
+	}
 }
 
 func AddPrompts(s *server.MCPServer) {
 	s.AddPrompt(mcp.NewPrompt("explain_pipeline_error",
 		mcp.WithPromptDescription("Explain the error of a Pipeline"),
-		mcp.WithArgument("pipeline", mcp.RequiredArgument(),
-			mcp.ArgumentDescription("Name of the Pipeline"),
+		mcp.WithArgument("pipeline",
+			mcp.ArgumentDescription("Name of the Pipeline, its most recent failed PipelineRun is explained"),
+		),
+		mcp.WithArgument("pipelinerun",
+			mcp.ArgumentDescription("Name of the PipelineRun to explain, takes precedence over pipeline"),
+		),
+		mcp.WithArgument("namespace",
+			mcp.ArgumentDescription("Namespace of the Pipeline or PipelineRun, defaults to the server's default namespace"),
 		),
 	), handlerExplainPipelineError)
 }
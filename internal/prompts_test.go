@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	fakepipelinerun "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun/fake"
+	faketaskrun "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun/fake"
+	_ "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun/fake"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	rtesting "knative.dev/pkg/reconciler/testing"
+)
+
+func TestExplainPipelineErrorAuthorization(t *testing.T) {
+	failed := duckv1.Status{Conditions: duckv1.Conditions{{Type: apis.ConditionSucceeded, Status: corev1.ConditionFalse, Reason: "Failed"}}}
+	pr := &v1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "build-x7k2", Namespace: "default"}}
+	pr.Status.Status = failed
+	tr := &v1.TaskRun{ObjectMeta: metav1.ObjectMeta{
+		Name: "build-x7k2-compile", Namespace: "default",
+		Labels: map[string]string{pipeline.PipelineRunLabelKey: "build-x7k2", pipeline.PipelineTaskLabelKey: "compile"},
+	}}
+	tr.Status.Status = failed
+	tr.Status.PodName = "build-x7k2-compile-pod"
+	tr.Status.Steps = []v1.StepState{{
+		Name:           "build",
+		Container:      "step-build",
+		ContainerState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}},
+	}}
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "e1", Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "build-x7k2-compile-pod"},
+		Type:           "Warning",
+		Reason:         "OOMKilled",
+	}
+
+	tests := []struct {
+		name     string
+		denied   []string
+		contains []string
+		excludes []string
+	}{{
+		name:     "allowed",
+		contains: []string{"Failed TaskRun build-x7k2-compile", "step build terminated with exit code 2", "Warning Pod/build-x7k2-compile-pod: OOMKilled", "tekton://taskrun/default/build-x7k2-compile/logs"},
+		excludes: []string{"Not included"},
+	}, {
+		name:     "no taskruns",
+		denied:   []string{"taskruns"},
+		contains: []string{"Not included because the caller is not allowed to read them: failed TaskRuns."},
+		excludes: []string{"Failed TaskRun", "tekton://taskrun/"},
+	}, {
+		name:     "no logs",
+		denied:   []string{"pods/log"},
+		contains: []string{"Failed TaskRun build-x7k2-compile", "Not included because the caller is not allowed to read them: step logs."},
+		excludes: []string{"tekton://taskrun/default/build-x7k2-compile/logs"},
+	}, {
+		name:     "no events",
+		denied:   []string{"events"},
+		contains: []string{"Failed TaskRun build-x7k2-compile", "Not included because the caller is not allowed to read them: Kubernetes events."},
+		excludes: []string{"OOMKilled"},
+	}, {
+		name:     "no customruns",
+		denied:   []string{"customruns"},
+		contains: []string{"Not included because the caller is not allowed to read them: failed CustomRuns."},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, _ := rtesting.SetupFakeContext(t)
+			for _, obj := range []metav1.Object{pr, tr} {
+				var err error
+				switch o := obj.(type) {
+				case *v1.PipelineRun:
+					err = fakepipelinerun.Get(ctx).Informer().GetIndexer().Add(o)
+				case *v1.TaskRun:
+					err = faketaskrun.Get(ctx).Informer().GetIndexer().Add(o)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			kube := fakekubeclient.Get(ctx)
+			if _, err := kube.CoreV1().Events("default").Create(ctx, event, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			kube.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				target := review.Spec.ResourceAttributes.Resource
+				if sub := review.Spec.ResourceAttributes.Subresource; sub != "" {
+					target += "/" + sub
+				}
+				review.Status.Allowed = true
+				for _, denied := range test.denied {
+					if denied == target {
+						review.Status.Allowed = false
+					}
+				}
+				return true, review, nil
+			})
+			ctx = context.WithValue(ctx, userKey{}, &User{Name: "alice"})
+
+			request := mcp.GetPromptRequest{}
+			request.Params.Arguments = map[string]string{"pipelinerun": "build-x7k2", "namespace": "default"}
+			result, err := handlerExplainPipelineError(ctx, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			for _, m := range result.Messages {
+				switch c := m.Content.(type) {
+				case mcp.TextContent:
+					b.WriteString(c.Text)
+				case mcp.EmbeddedResource:
+					b.WriteString(c.Resource.(mcp.TextResourceContents).URI)
+				}
+				b.WriteString("\n")
+			}
+			text := b.String()
+			for _, s := range test.contains {
+				if !strings.Contains(text, s) {
+					t.Errorf("prompt doesn't contain %q:\n%s", s, text)
+				}
+			}
+			for _, s := range test.excludes {
+				if strings.Contains(text, s) {
+					t.Errorf("prompt contains %q:\n%s", s, text)
+				}
+			}
+		})
+	}
+}
+
+func TestExplainPipelineErrorNotFailed(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition *apis.Condition
+		want      string
+		title     string
+	}{{
+		name:      "succeeded",
+		condition: &apis.Condition{Type: apis.ConditionSucceeded, Status: corev1.ConditionTrue, Reason: "Succeeded"},
+		want:      "The PipelineRun default/build-x7k2 succeeded, there is no failure to explain. Tell the user that it succeeded.",
+		title:     "PipelineRun default/build-x7k2 succeeded",
+	}, {
+		name:      "running",
+		condition: &apis.Condition{Type: apis.ConditionSucceeded, Status: corev1.ConditionUnknown, Reason: "Running"},
+		want:      "The PipelineRun default/build-x7k2 is still running, there is no failure to explain. Tell the user that it is still running.",
+		title:     "PipelineRun default/build-x7k2 is still running",
+	}, {
+		name:  "not started",
+		want:  "The PipelineRun default/build-x7k2 is still running, there is no failure to explain.",
+		title: "PipelineRun default/build-x7k2 is still running",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, _ := rtesting.SetupFakeContext(t)
+			pr := &v1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "build-x7k2", Namespace: "default"}}
+			if test.condition != nil {
+				pr.Status.Conditions = duckv1.Conditions{*test.condition}
+			}
+			if err := fakepipelinerun.Get(ctx).Informer().GetIndexer().Add(pr); err != nil {
+				t.Fatal(err)
+			}
+
+			request := mcp.GetPromptRequest{}
+			request.Params.Arguments = map[string]string{"pipelinerun": "build-x7k2", "namespace": "default"}
+			result, err := handlerExplainPipelineError(ctx, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Description != test.title {
+				t.Errorf("got description %q, want %q", result.Description, test.title)
+			}
+			if len(result.Messages) != 1 {
+				t.Fatalf("got %d messages, want 1", len(result.Messages))
+			}
+			text := result.Messages[0].Content.(mcp.TextContent).Text
+			if !strings.HasPrefix(text, test.want) || strings.Contains(text, "Explain why it failed") {
+				t.Errorf("got prompt %q, want it to start with %q", text, test.want)
+			}
+		})
+	}
+}
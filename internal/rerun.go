@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
+	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
+	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RerunOfLabelKey is the label key used to link a run created by the rerun tool to the run it was cloned from
+const RerunOfLabelKey = "mcp.openshift-pipelines.org/rerun-of"
+
+func toolRerun() mcp.Tool {
+	return mcp.NewTool("rerun",
+		mcp.WithDescription("Run again a previous PipelineRun or TaskRun, with the same inputs and optionally some params overridden"),
+		mcp.WithString("kind", mcp.Required(),
+			mcp.Description("Kind of the run to rerun"),
+			mcp.Enum("pipelinerun", "taskrun"),
+		),
+		mcp.WithString("name", mcp.Required(),
+			mcp.Description("Name of the run to rerun"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace where the run is located, defaults to the server's default namespace"),
+		),
+		mcp.WithObject("params",
+			mcp.Description("Params to override, as an object mapping each param name to a string, an array of strings or an object with string values"),
+		),
+		mcp.WithBoolean("wait",
+			mcp.Description("Wait for the new run to finish and return its final status and results"),
+		),
+		mcp.WithString("wait-timeout",
+			mcp.Description("How long to wait for the new run to finish, as a duration (e.g. 30m), defaults to 10m"),
+		),
+	)
+}
+
+func handlerRerun(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	kind, err := OptionalParam[string](request, "kind")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	name, err := OptionalParam[string](request, "name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	overrides, errs := parseRunParams(request)
+	wait, waitTimeout, err := waitParams(request)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	switch kind {
+	case "pipelinerun":
+		return rerunPipelineRun(ctx, request, namespace, name, overrides, errs, wait, waitTimeout)
+	case "taskrun":
+		return rerunTaskRun(ctx, request, namespace, name, overrides, errs, wait, waitTimeout)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("kind %q is not one of pipelinerun or taskrun", kind)), nil
+	}
+}
+
+func rerunPipelineRun(ctx context.Context, request mcp.CallToolRequest, namespace, name string, overrides v1.Params, errs []string, wait bool, waitTimeout time.Duration) (*mcp.CallToolResult, error) {
+	origin, err := pipelineruninformer.Get(ctx).Lister().PipelineRuns(namespace).Get(name)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to get PipelineRun %s/%s: %v", namespace, name, err)), nil
+	}
+
+	spec := origin.Spec.DeepCopy()
+	spec.Status = ""
+	spec.Params = overrideParams(spec.Params, overrides)
+	if origin.Status.PipelineSpec != nil {
+		errs = append(errs, validateRunParams(origin.Status.PipelineSpec.Params, spec.Params)...)
+	}
+	if len(errs) > 0 {
+		return invalidRunError("PipelineRun", namespace, name, errs), nil
+	}
+
+	pr := &v1.PipelineRun{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "tekton.dev/v1",
+			Kind:       "PipelineRun",
+		},
+		ObjectMeta: rerunObjectMeta(origin.ObjectMeta),
+		Spec:       *spec,
+	}
+
+	created, err := pipelineclient.Get(ctx).TektonV1().PipelineRuns(namespace).Create(ctx, pr, metav1.CreateOptions{})
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to create PipelineRun from %s/%s: %v", namespace, name, err)), nil
+	}
+
+	if !wait {
+		return runResult("pipelinerun", created, fmt.Sprintf("Started a rerun of PipelineRun %s", name), nil)
+	}
+
+	run, done, err := waitForPipelineRun(ctx, request, namespace, created.Name, waitTimeout)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to wait for PipelineRun %s/%s: %v", namespace, created.Name, err)), nil
+	}
+	outcome := pipelineRunOutcome(ctx, run)
+	return runResult("pipelinerun", run, waitedRunSummary("PipelineRun", outcome, done), outcome)
+}
+
+func rerunTaskRun(ctx context.Context, request mcp.CallToolRequest, namespace, name string, overrides v1.Params, errs []string, wait bool, waitTimeout time.Duration) (*mcp.CallToolResult, error) {
+	origin, err := taskruninformer.Get(ctx).Lister().TaskRuns(namespace).Get(name)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to get TaskRun %s/%s: %v", namespace, name, err)), nil
+	}
+
+	spec := origin.Spec.DeepCopy()
+	spec.Status = ""
+	spec.StatusMessage = ""
+	spec.Params = overrideParams(spec.Params, overrides)
+	if origin.Status.TaskSpec != nil {
+		errs = append(errs, validateRunParams(origin.Status.TaskSpec.Params, spec.Params)...)
+	}
+	if len(errs) > 0 {
+		return invalidRunError("TaskRun", namespace, name, errs), nil
+	}
+
+	tr := &v1.TaskRun{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "tekton.dev/v1",
+			Kind:       "TaskRun",
+		},
+		ObjectMeta: rerunObjectMeta(origin.ObjectMeta),
+		Spec:       *spec,
+	}
+
+	created, err := pipelineclient.Get(ctx).TektonV1().TaskRuns(namespace).Create(ctx, tr, metav1.CreateOptions{})
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to create TaskRun from %s/%s: %v", namespace, name, err)), nil
+	}
+
+	if !wait {
+		return runResult("taskrun", created, fmt.Sprintf("Started a rerun of TaskRun %s", name), nil)
+	}
+
+	run, done, err := waitForTaskRun(ctx, request, namespace, created.Name, waitTimeout)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to wait for TaskRun %s/%s: %v", namespace, created.Name, err)), nil
+	}
+	outcome := taskRunOutcome(run)
+	return runResult("taskrun", run, waitedRunSummary("TaskRun", outcome, done), outcome)
+}
+
+// rerunObjectMeta builds the metadata of a rerun from the metadata of the original run:
+// user labels and annotations are kept, generated metadata, the labels set by Tekton Pipelines
+// and Tekton Triggers and the annotations set by Tekton Chains and Tekton Results are dropped, so
+// that the rerun is only linked to the run it was cloned from.
+func rerunObjectMeta(origin metav1.ObjectMeta) metav1.ObjectMeta {
+	generateName := origin.GenerateName
+	if generateName == "" {
+		generateName = fmt.Sprintf("%s-", origin.Name)
+	}
+
+	labels := map[string]string{}
+	for k, v := range origin.Labels {
+		if strings.HasPrefix(k, "tekton.dev/") || strings.HasPrefix(k, "triggers.tekton.dev/") {
+			continue
+		}
+		labels[k] = v
+	}
+	labels[RerunOfLabelKey] = origin.Name
+
+	annotations := map[string]string{}
+	for k, v := range origin.Annotations {
+		if strings.HasPrefix(k, "chains.tekton.dev/") || strings.HasPrefix(k, "results.tekton.dev/") || k == "kubectl.kubernetes.io/last-applied-configuration" {
+			continue
+		}
+		annotations[k] = v
+	}
+
+	return metav1.ObjectMeta{
+		Namespace:    origin.Namespace,
+		GenerateName: generateName,
+		Labels:       labels,
+		Annotations:  annotations,
+	}
+}
+
+// overrideParams replaces the params of a run with the given overrides, adding the ones that are missing.
+func overrideParams(params v1.Params, overrides v1.Params) v1.Params {
+	result := make(v1.Params, 0, len(params)+len(overrides))
+	replaced := map[string]bool{}
+	for _, p := range params {
+		for _, o := range overrides {
+			if o.Name == p.Name {
+				p = o
+				replaced[o.Name] = true
+				break
+			}
+		}
+		result = append(result, p)
+	}
+	for _, o := range overrides {
+		if !replaced[o.Name] {
+			result = append(result, o)
+		}
+	}
+	return result
+}
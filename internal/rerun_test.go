@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRerunObjectMeta(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin metav1.ObjectMeta
+		want   metav1.ObjectMeta
+	}{{
+		name: "user metadata is kept",
+		origin: metav1.ObjectMeta{
+			Name: "build-x7k2", Namespace: "default", UID: "uid", ResourceVersion: "42",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{"team": "a"},
+		},
+		want: metav1.ObjectMeta{
+			Namespace: "default", GenerateName: "build-x7k2-",
+			Labels:      map[string]string{"app": "web", RerunOfLabelKey: "build-x7k2"},
+			Annotations: map[string]string{"team": "a"},
+		},
+	}, {
+		name: "generated metadata is dropped",
+		origin: metav1.ObjectMeta{
+			Name: "build-x7k2", GenerateName: "build-", Namespace: "default",
+			Labels: map[string]string{
+				"tekton.dev/pipeline":                 "build",
+				TriggersEventListenerLabelKey:         "github",
+				TriggersTriggerLabelKey:               "push",
+				TriggersEventIDLabelKey:               "c0ffee",
+				"app.kubernetes.io/managed-by":        "tekton-pipelines",
+				"tekton.dev/pipelineRun":              "build-x7k2",
+				"triggers.tekton.dev/custom-interval": "5m",
+			},
+			Annotations: map[string]string{
+				"chains.tekton.dev/signed":                         "true",
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		want: metav1.ObjectMeta{
+			Namespace: "default", GenerateName: "build-",
+			Labels:      map[string]string{"app.kubernetes.io/managed-by": "tekton-pipelines", RerunOfLabelKey: "build-x7k2"},
+			Annotations: map[string]string{},
+		},
+	}, {
+		name: "results annotations are dropped",
+		origin: metav1.ObjectMeta{
+			Name: "build-x7k2", Namespace: "default",
+			Annotations: map[string]string{
+				"results.tekton.dev/result": "default/results/0d5c5b5e",
+				"results.tekton.dev/record": "default/results/0d5c5b5e/records/0d5c5b5e",
+				"results.tekton.dev/log":    "default/results/0d5c5b5e/logs/0d5c5b5e",
+				"team":                      "a",
+			},
+		},
+		want: metav1.ObjectMeta{
+			Namespace: "default", GenerateName: "build-x7k2-",
+			Labels:      map[string]string{RerunOfLabelKey: "build-x7k2"},
+			Annotations: map[string]string{"team": "a"},
+		},
+	}, {
+		name: "rerun of a rerun",
+		origin: metav1.ObjectMeta{
+			Name: "build-x7k2-abcde", GenerateName: "build-x7k2-", Namespace: "default",
+			Labels: map[string]string{RerunOfLabelKey: "build-x7k2"},
+		},
+		want: metav1.ObjectMeta{
+			Namespace: "default", GenerateName: "build-x7k2-",
+			Labels:      map[string]string{RerunOfLabelKey: "build-x7k2-abcde"},
+			Annotations: map[string]string{},
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rerunObjectMeta(test.origin); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// defaultMaxResources is the maximum number of objects listed by resources/list, when not configured.
+	defaultMaxResources = 500
+	// resourcesPageSize is the number of objects returned by each resources/list call.
+	resourcesPageSize = 100
+)
+
+// ListCachedResources is a hook adding the objects of the informer caches to the result of
+// resources/list, as tekton:// resources, along with the summaries of their namespaces. The objects
+// are sorted by URI, the namespaces denied by the configuration and the objects the caller is not
+// allowed to list are skipped, and at most the configured maximum number of objects are listed, in
+// pages of resourcesPageSize.
+func ListCachedResources(ctx context.Context, _ any, request *mcp.ListResourcesRequest, result *mcp.ListResourcesResult) {
+	cfg := ConfigFromContext(ctx)
+
+	// The static resources are listed on the first page only
+	var after string
+	if request.Params.Cursor != "" {
+		// The server already checked that the cursor is valid base64
+		c, _ := base64.StdEncoding.DecodeString(string(request.Params.Cursor))
+		after = string(c)
+		result.Resources = nil
+	}
+
+	var resources []mcp.Resource
+	canList := listAuthorizer(ctx)
+	namespaces := map[string]bool{}
+	for _, k := range listKinds {
+		objs, err := listCached(k.informer(ctx), "", labels.Everything())
+		if err != nil {
+			slog.Error(fmt.Sprintf("failed to list %ss: %v", k.kind, err))
+			continue
+		}
+		for _, obj := range objs {
+			namespace := obj.GetNamespace()
+			if !cfg.Namespaces.Allowed(namespace) {
+				continue
+			}
+			if !canList(k, namespace) {
+				continue
+			}
+			namespaces[namespace] = true
+			resources = append(resources, mcp.NewResource(
+				TektonResourceURI(k.resourceType, namespace, obj.GetName()),
+				fmt.Sprintf("%s %s/%s", k.kind, namespace, obj.GetName()),
+				mcp.WithMIMEType(fmt.Sprintf("application/json;type=%s", k.resourceType)),
+			))
+		}
+	}
+
+	for namespace := range namespaces {
+		resources = append(resources, mcp.NewResource(
+			TektonNamespaceURI(namespace),
+			fmt.Sprintf("Namespace %s", namespace),
+			mcp.WithMIMEType("application/json"),
+		))
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].URI < resources[j].URI
+	})
+	maxResources := cfg.MaxResources
+	if maxResources <= 0 {
+		maxResources = defaultMaxResources
+	}
+	if len(resources) > maxResources {
+		resources = resources[:maxResources]
+	}
+
+	start := sort.Search(len(resources), func(i int) bool {
+		return resources[i].URI > after
+	})
+	end := min(start+resourcesPageSize, len(resources))
+	result.Resources = append(result.Resources, resources[start:end]...)
+	result.NextCursor = ""
+	if end < len(resources) {
+		result.NextCursor = mcp.Cursor(base64.StdEncoding.EncodeToString([]byte(resources[end-1].URI)))
+	}
+}
+
+// listAuthorizer returns a function telling whether the caller is allowed to list a kind in a
+// namespace, which checks each kind and namespace once.
+func listAuthorizer(ctx context.Context) func(k listKind, namespace string) bool {
+	allowed := map[string]bool{}
+	return func(k listKind, namespace string) bool {
+		key := fmt.Sprintf("%s/%s", k.resource(), namespace)
+		ok, checked := allowed[key]
+		if !checked {
+			ok = authorize(ctx, "list", k.group, k.resource(), "", namespace, "") == nil
+			allowed[key] = ok
+		}
+		return ok
+	}
+}
@@ -10,56 +10,83 @@ import (
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
-	pipelineinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipeline"
-	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
-	taskinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/task"
-	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
-	stepactioninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/stepaction"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func AddResources(ctx context.Context, s *server.MCPServer) {
 	s.AddResourceTemplate(GetPipelineRunResourceContent(ctx))
 	s.AddResourceTemplate(GetTaskRunResourceContent(ctx))
+	s.AddResourceTemplate(GetCustomRunResourceContent(ctx))
 	s.AddResourceTemplate(GetPipelineResourceContent(ctx))
 	s.AddResourceTemplate(GetTaskResourceContent(ctx))
 	s.AddResourceTemplate(GetStepActionResourceContent(ctx))
+	addTriggersResources(ctx, s)
+	s.AddResourceTemplate(GetTaskRunLogsResourceContent(ctx))
+	s.AddResource(GetNamespacesResourceContent(ctx))
+	s.AddResourceTemplate(GetNamespaceResourceContent(ctx))
 }
 
 func GetPipelineRunResourceContent(ctx context.Context) (mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc) {
 	return mcp.NewResourceTemplate(
-		"tekton://pipelinerun/{namespace}/{name}",
+		"tekton://pipelinerun/{namespace}/{name}{?format}",
 		"PipelineRun",
 	), TektonResourceContentHandler(ctx)
 }
 
 func GetTaskRunResourceContent(ctx context.Context) (mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc) {
 	return mcp.NewResourceTemplate(
-		"tekton://taskrun/{namespace}/{name}",
+		"tekton://taskrun/{namespace}/{name}{?format}",
 		"TaskRun",
 	), TektonResourceContentHandler(ctx)
 }
 
+func GetCustomRunResourceContent(ctx context.Context) (mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc) {
+	return mcp.NewResourceTemplate(
+		"tekton://customrun/{namespace}/{name}{?format}",
+		"CustomRun",
+	), TektonResourceContentHandler(ctx)
+}
+
 func GetPipelineResourceContent(ctx context.Context) (mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc) {
 	return mcp.NewResourceTemplate(
-		"tekton://pipeline/{namespace}/{name}",
+		"tekton://pipeline/{namespace}/{name}{?format}",
 		"Pipeline",
 	), TektonResourceContentHandler(ctx)
 }
 
 func GetTaskResourceContent(ctx context.Context) (mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc) {
 	return mcp.NewResourceTemplate(
-		"tekton://task/{namespace}/{name}",
+		"tekton://task/{namespace}/{name}{?format}",
 		"Task",
 	), TektonResourceContentHandler(ctx)
 }
 
 func GetStepActionResourceContent(ctx context.Context) (mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc) {
 	return mcp.NewResourceTemplate(
-		"tekton://stepaction/{namespace}/{name}",
+		"tekton://stepaction/{namespace}/{name}{?format}",
 		"StepAction",
 	), TektonResourceContentHandler(ctx)
 }
 
+// addTriggersResources adds the resource templates of the Tekton Triggers kinds.
+func addTriggersResources(ctx context.Context, s *server.MCPServer) {
+	for _, k := range listKinds {
+		if k.group != triggersGroup {
+			continue
+		}
+		s.AddResourceTemplate(mcp.NewResourceTemplate(
+			fmt.Sprintf("tekton://%s/{namespace}/{name}{?format}", k.resourceType),
+			k.kind,
+		), TektonResourceContentHandler(ctx))
+	}
+}
+
+// TektonResourceURI returns the tekton:// URI of the given object.
+func TektonResourceURI(resourceType, namespace, name string) string {
+	return fmt.Sprintf("tekton://%s/%s/%s", resourceType, namespace, name)
+}
+
 func TektonResourceContentHandler(ctx context.Context) server.ResourceTemplateHandlerFunc {
 	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
 		ns, ok := request.Params.Arguments["namespace"].([]string)
@@ -67,6 +94,9 @@ func TektonResourceContentHandler(ctx context.Context) server.ResourceTemplateHa
 			return nil, errors.New("namespace is required")
 		}
 		namespace := ns[0]
+		if err := ConfigFromContext(ctx).CheckNamespace(namespace); err != nil {
+			return nil, err
+		}
 
 		n, ok := request.Params.Arguments["name"].([]string)
 		if !ok || len(n) == 0 {
@@ -76,118 +106,66 @@ func TektonResourceContentHandler(ctx context.Context) server.ResourceTemplateHa
 
 		uri := request.Params.URI
 		resourceType := strings.Split(uri, "/")[2]
+		k, ok := listKindOf(resourceType)
+		if !ok {
+			return nil, fmt.Errorf("unknown resource type %s", resourceType)
+		}
+
+		format := "json"
+		if f, ok := request.Params.Arguments["format"].([]string); ok && len(f) > 0 && f[0] != "" {
+			format = f[0]
+		}
+		if format != "json" && format != "yaml" {
+			return nil, fmt.Errorf("format must be json or yaml, not %q", format)
+		}
 
-		var jsonData []byte
-		var err error
+		if err := authorize(ctx, "get", k.group, k.resource(), "", namespace, name); err != nil {
+			return nil, err
+		}
 
 		slog.Info(fmt.Sprintf("Resource: %s, %s/%s", resourceType, namespace, name))
 
-		switch resourceType {
-		case "pipelinerun":
-			jsonData, err = getPipelineRun(ctx, namespace, name)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get PipelineRun %s/%s: %w", namespace, name, err)
-			}
-		case "taskrun":
-			jsonData, err = getTaskRun(ctx, namespace, name)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get TaskRun %s/%s: %w", namespace, name, err)
-			}
-		case "pipeline":
-			jsonData, err = getPipeline(ctx, namespace, name)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get Pipeline %s/%s: %w", namespace, name, err)
+		contentType := fmt.Sprintf("application/%s;type=%s", format, resourceType)
+		obj, err := k.getCached(ctx, namespace, name)
+		if apierrors.IsNotFound(err) {
+			// The run may have been deleted from the cluster and archived by Tekton Results
+			archived, _, resultsErr := getArchived(ctx, k, namespace, name)
+			if resultsErr != nil {
+				err = fmt.Errorf("%w, and failed to read Tekton Results: %w", err, resultsErr)
 			}
-		case "task":
-			jsonData, err = getTask(ctx, namespace, name)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get Task %s/%s: %w", namespace, name, err)
+			if archived != nil {
+				obj, err = archived.(runtime.Object), nil
+				contentType = fmt.Sprintf("%s;source=results", contentType)
 			}
-		case "stepaction":
-			jsonData, err = getStepAction(ctx, namespace, name)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get StepAction %s/%s: %w", namespace, name, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s %s/%s: %w", k.kind, namespace, name, err)
+		}
+
+		var data []byte
+		if format == "yaml" {
+			if data, err = objectYAML(obj); err != nil {
+				return nil, err
 			}
+		} else if data, err = objectJSON(obj); err != nil {
+			return nil, err
 		}
 
-		contentType := fmt.Sprintf("application/json;type=%s", resourceType)
 		contents := mcp.TextResourceContents{
 			URI:      uri,
 			MIMEType: contentType,
-			Text:     string(jsonData),
+			Text:     string(data),
 		}
 
 		return []mcp.ResourceContents{contents}, nil
 	}
 }
 
-func getPipelineRun(ctx context.Context, namespace string, name string) ([]byte, error) {
-	pipelineRunInformer := pipelineruninformer.Get(ctx)
-	pipelineRun, err := pipelineRunInformer.Lister().PipelineRuns(namespace).Get(name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get PipelineRun %s/%s: %w", namespace, name, err)
-	}
-	slog.Info(fmt.Sprintf("PipelineRun %s: %v", pipelineRun.Name, pipelineRun))
-
-	jsonData, err := json.Marshal(pipelineRun)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
-	}
-	return jsonData, nil
-}
-
-func getTaskRun(ctx context.Context, namespace string, name string) ([]byte, error) {
-	taskRunInformer := taskruninformer.Get(ctx)
-	taskRun, err := taskRunInformer.Lister().TaskRuns(namespace).Get(name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get TaskRun %s/%s: %w", namespace, name, err)
-	}
-
-	jsonData, err := json.Marshal(taskRun)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
-	}
-	return jsonData, nil
-}
-
-func getPipeline(ctx context.Context, namespace string, name string) ([]byte, error) {
-	pipelineInformer := pipelineinformer.Get(ctx)
-	pipeline, err := pipelineInformer.Lister().Pipelines(namespace).Get(name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get Pipeline %s/%s: %w", namespace, name, err)
-	}
-
-	jsonData, err := json.Marshal(pipeline)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
-	}
-	return jsonData, nil
-}
-
-func getTask(ctx context.Context, namespace string, name string) ([]byte, error) {
-	taskInformer := taskinformer.Get(ctx)
-	task, err := taskInformer.Lister().Tasks(namespace).Get(name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get Task %s/%s: %w", namespace, name, err)
-	}
-
-	jsonData, err := json.Marshal(task)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
-	}
-	return jsonData, nil
-}
-
-func getStepAction(ctx context.Context, namespace string, name string) ([]byte, error) {
-	stepActionInformer := stepactioninformer.Get(ctx)
-	stepAction, err := stepActionInformer.Lister().StepActions(namespace).Get(name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get StepAction %s/%s: %w", namespace, name, err)
-	}
-
-	jsonData, err := json.Marshal(stepAction)
+// objectJSON renders an object as the JSON content of its tekton:// resource.
+func objectJSON(obj runtime.Object) ([]byte, error) {
+	data, err := json.Marshal(obj)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
 	}
-	return jsonData, nil
+	return data, nil
 }
@@ -0,0 +1,384 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// resultsPageSize is the number of records requested in each call to Tekton Results.
+	resultsPageSize = 100
+	// maxResultsRecords is the maximum number of records read from Tekton Results by a list tool.
+	maxResultsRecords = 1000
+	// resultsTimeout is how long a call to Tekton Results can take.
+	resultsTimeout = 30 * time.Second
+)
+
+// resultsRecordTypes are the types of the records Tekton Results archives for each listed kind.
+var resultsRecordTypes = map[string][]string{
+	"pipelinerun": {"tekton.dev/v1.PipelineRun", "tekton.dev/v1beta1.PipelineRun"},
+	"taskrun":     {"tekton.dev/v1.TaskRun", "tekton.dev/v1beta1.TaskRun"},
+}
+
+// ResultsClient reads the runs and logs archived by Tekton Results, through its REST API. The runs
+// deleted from the cluster (e.g. by a pruner) are served from their records.
+type ResultsClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewResultsClient returns a client of the Tekton Results API served at baseURL, authenticated with
+// the credentials of the Kubernetes configuration. caFile is the CA bundle of the API server
+// certificate, the system roots are used if it is empty.
+func NewResultsClient(baseURL, caFile string, cfg *rest.Config) (*ResultsClient, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid Tekton Results URL %q", baseURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	authenticated, err := rest.HTTPWrappersForConfig(cfg, transport)
+	if err != nil {
+		return nil, err
+	}
+	return &ResultsClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Transport: authenticated, Timeout: resultsTimeout},
+	}, nil
+}
+
+type resultsKey struct{}
+
+// WithResults stores the Tekton Results client in the context, for the handlers to fall back to it.
+func WithResults(ctx context.Context, c *ResultsClient) context.Context {
+	return context.WithValue(ctx, resultsKey{}, c)
+}
+
+// resultsFromContext returns the Tekton Results client, or nil if Results is not configured.
+func resultsFromContext(ctx context.Context) *ResultsClient {
+	c, _ := ctx.Value(resultsKey{}).(*ResultsClient)
+	return c
+}
+
+// resultsRecord is a record of Tekton Results, holding an object archived by its watcher.
+type resultsRecord struct {
+	// Name is the name of the record, <namespace>/results/<result>/records/<record>.
+	Name string `json:"name"`
+	UID  string `json:"uid"`
+	Data struct {
+		Type  string `json:"type"`
+		Value []byte `json:"value"`
+	} `json:"data"`
+}
+
+type resultsRecordList struct {
+	Records       []resultsRecord `json:"records"`
+	NextPageToken string          `json:"nextPageToken"`
+}
+
+// resultsError is the body of the errors returned by the Results API.
+type resultsError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// get calls the Results API, returning an error for the responses that are not successful.
+func (c *ResultsClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u = fmt.Sprintf("%s?%s", u, query.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Tekton Results: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var e resultsError
+	if json.Unmarshal(body, &e) == nil && e.Message != "" {
+		return nil, fmt.Errorf("Tekton Results returned %s: %s", resp.Status, e.Message)
+	}
+	return nil, fmt.Errorf("Tekton Results returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// listRecords returns the most recent records of a namespace, or of all namespaces if namespace is
+// empty, matching the CEL filter. It returns at most limit records, and whether there are more.
+func (c *ResultsClient) listRecords(ctx context.Context, namespace, filter string, limit int) ([]resultsRecord, bool, error) {
+	parent := namespace
+	if parent == "" {
+		parent = "-"
+	}
+	path := fmt.Sprintf("/apis/results.tekton.dev/v1alpha2/parents/%s/results/-/records", url.PathEscape(parent))
+
+	var records []resultsRecord
+	var pageToken string
+	for {
+		query := url.Values{
+			"filter":    {filter},
+			"order_by":  {"create_time desc"},
+			"page_size": {strconv.Itoa(min(resultsPageSize, limit-len(records)))},
+		}
+		if pageToken != "" {
+			query.Set("page_token", pageToken)
+		}
+		resp, err := c.get(ctx, path, query)
+		if err != nil {
+			return nil, false, err
+		}
+		var page resultsRecordList
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode the records of Tekton Results: %w", err)
+		}
+
+		records = append(records, page.Records...)
+		pageToken = page.NextPageToken
+		if pageToken == "" {
+			return records, false, nil
+		}
+		if len(records) >= limit {
+			return records[:limit], true, nil
+		}
+	}
+}
+
+// resultsFilter returns the CEL filter of the records of a kind, and the additional conditions.
+func resultsFilter(resourceType string, conditions ...string) string {
+	var dataTypes []string
+	for _, t := range resultsRecordTypes[resourceType] {
+		dataTypes = append(dataTypes, fmt.Sprintf("data_type == %s", strconv.Quote(t)))
+	}
+	return strings.Join(append([]string{fmt.Sprintf("(%s)", strings.Join(dataTypes, " || "))}, conditions...), " && ")
+}
+
+// decodeRecord returns the run archived in a record, converted to the version of the informers.
+func decodeRecord(ctx context.Context, r resultsRecord) (metav1.Object, error) {
+	var obj metav1.Object
+	var err error
+	switch r.Data.Type {
+	case "tekton.dev/v1.PipelineRun":
+		pr := &v1.PipelineRun{}
+		err = json.Unmarshal(r.Data.Value, pr)
+		obj = pr
+	case "tekton.dev/v1beta1.PipelineRun":
+		old, pr := &v1beta1.PipelineRun{}, &v1.PipelineRun{}
+		if err = json.Unmarshal(r.Data.Value, old); err == nil {
+			err = old.ConvertTo(ctx, pr)
+		}
+		obj = pr
+	case "tekton.dev/v1.TaskRun":
+		tr := &v1.TaskRun{}
+		err = json.Unmarshal(r.Data.Value, tr)
+		obj = tr
+	case "tekton.dev/v1beta1.TaskRun":
+		old, tr := &v1beta1.TaskRun{}, &v1.TaskRun{}
+		if err = json.Unmarshal(r.Data.Value, old); err == nil {
+			err = old.ConvertTo(ctx, tr)
+		}
+		obj = tr
+	default:
+		return nil, fmt.Errorf("record %s holds a %s, not a run", r.Name, r.Data.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode record %s: %w", r.Name, err)
+	}
+	return obj, nil
+}
+
+// archivedList is the result of listing the runs archived in Tekton Results.
+type archivedList struct {
+	objs []metav1.Object
+	// records are the names of the records of the objects, by UID.
+	records map[types.UID]string
+	// truncated is true if only the most recent records were read.
+	truncated bool
+}
+
+// listArchived returns the runs of the kind archived in Tekton Results that are no longer in the
+// informer cache, in a namespace or in all namespaces if namespace is empty. It returns an empty list
+// if Results is not configured or doesn't archive the kind.
+func listArchived(ctx context.Context, k listKind, namespace, prefix string, selector labels.Selector, cached []metav1.Object) (archivedList, error) {
+	archived := archivedList{records: map[types.UID]string{}}
+	c := resultsFromContext(ctx)
+	if c == nil || resultsRecordTypes[k.resourceType] == nil {
+		return archived, nil
+	}
+
+	var conditions []string
+	if prefix != "" {
+		conditions = append(conditions, fmt.Sprintf("data.metadata.name.startsWith(%s)", strconv.Quote(prefix)))
+	}
+	records, truncated, err := c.listRecords(ctx, namespace, resultsFilter(k.resourceType, conditions...), maxResultsRecords)
+	if err != nil {
+		return archived, err
+	}
+	archived.truncated = truncated
+
+	inCluster := map[types.UID]bool{}
+	for _, obj := range cached {
+		inCluster[obj.GetUID()] = true
+	}
+	var errs []error
+	for _, r := range records {
+		obj, err := decodeRecord(ctx, r)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// A run is archived several times while it executes, the most recent record comes first
+		if inCluster[obj.GetUID()] || archived.records[obj.GetUID()] != "" || !selector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		archived.objs = append(archived.objs, obj)
+		archived.records[obj.GetUID()] = r.Name
+	}
+	return archived, errors.Join(errs...)
+}
+
+// getArchived returns a run archived in Tekton Results, with the name of its record. It returns a
+// nil object if Results is not configured, doesn't archive the kind or has no record of the run.
+func getArchived(ctx context.Context, k listKind, namespace, name string) (metav1.Object, string, error) {
+	c := resultsFromContext(ctx)
+	if c == nil || resultsRecordTypes[k.resourceType] == nil {
+		return nil, "", nil
+	}
+	records, _, err := c.listRecords(ctx, namespace, resultsFilter(k.resourceType, fmt.Sprintf("data.metadata.name == %s", strconv.Quote(name))), 1)
+	if err != nil || len(records) == 0 {
+		return nil, "", err
+	}
+	obj, err := decodeRecord(ctx, records[0])
+	if err != nil {
+		return nil, "", err
+	}
+	return obj, records[0].Name, nil
+}
+
+// recordLogs returns the logs stored by Tekton Results for the run of a record. Results stores the
+// logs of all the steps of a TaskRun together, so only the tail-lines and limit-bytes options apply.
+func (c *ResultsClient) recordLogs(ctx context.Context, record string, opts logOptions) (string, error) {
+	// The logs of a run are named after its record: <namespace>/results/<result>/logs/<record>
+	parts := strings.Split(record, "/")
+	if len(parts) != 5 || parts[1] != "results" || parts[3] != "records" {
+		return "", fmt.Errorf("invalid record name %q", record)
+	}
+	path := fmt.Sprintf("/apis/results.tekton.dev/v1alpha3/parents/%s/results/%s/logs/%s",
+		url.PathEscape(parts[0]), url.PathEscape(parts[2]), url.PathEscape(parts[4]))
+	resp, err := c.get(ctx, path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if opts.tailLines == nil {
+		return readLogTail(resp.Body, opts.limitBytes)
+	}
+	tail := &tailBuffer{size: maxLogReadBytes}
+	if _, err := io.Copy(tail, resp.Body); err != nil {
+		return "", err
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(tail.bytes()), "\n"), "\n")
+	if n := int(*opts.tailLines); n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	return readLogTail(strings.NewReader(strings.Join(lines, "")), opts.limitBytes)
+}
+
+// archivedLogs returns the logs stored by Tekton Results for a PipelineRun or TaskRun that is no
+// longer in the cluster, and false if Results has no record of the run.
+func archivedLogs(ctx context.Context, k listKind, namespace, name string, opts logOptions) (string, bool, error) {
+	obj, record, err := getArchived(ctx, k, namespace, name)
+	if err != nil || obj == nil {
+		return "", false, err
+	}
+	c := resultsFromContext(ctx)
+
+	var b strings.Builder
+	switch run := obj.(type) {
+	case *v1.TaskRun:
+		b.WriteString(archivedTaskRunLogs(ctx, c, run, record, opts))
+	case *v1.PipelineRun:
+		filter := resultsFilter("taskrun", fmt.Sprintf("data.metadata.labels[%s] == %s", strconv.Quote(pipeline.PipelineRunLabelKey), strconv.Quote(name)))
+		records, _, err := c.listRecords(ctx, namespace, filter, maxResultsRecords)
+		if err != nil {
+			return "", true, err
+		}
+		var trs []*v1.TaskRun
+		trRecords := map[types.UID]string{}
+		for _, r := range records {
+			obj, err := decodeRecord(ctx, r)
+			if err != nil {
+				return "", true, err
+			}
+			// Older PipelineRuns of the same name have their own TaskRuns
+			tr, ok := obj.(*v1.TaskRun)
+			if ok && trRecords[tr.UID] == "" && metav1.IsControlledBy(tr, run) {
+				trs = append(trs, tr)
+				trRecords[tr.UID] = r.Name
+			}
+		}
+		sortTaskRunsInDAGOrder(run, trs)
+		for _, tr := range trs {
+			fmt.Fprintf(&b, "##### Task %s #####\n", tr.Labels[pipeline.PipelineTaskLabelKey])
+			b.WriteString(archivedTaskRunLogs(ctx, c, tr, trRecords[tr.UID], opts))
+		}
+		if len(trs) == 0 {
+			fmt.Fprintf(&b, "Tekton Results has no TaskRuns of PipelineRun %s\n", name)
+		}
+	}
+	return b.String(), true, nil
+}
+
+// archivedTaskRunLogs returns the logs of a TaskRun stored by Tekton Results. Errors are reported
+// inline, like the errors fetching the logs of a container.
+func archivedTaskRunLogs(ctx context.Context, c *ResultsClient, tr *v1.TaskRun, record string, opts logOptions) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== TaskRun %s (from Tekton Results, record %s) ===\n", tr.Name, record)
+	logs, err := c.recordLogs(ctx, record, opts)
+	if err != nil {
+		fmt.Fprintf(&b, "failed to get logs of TaskRun %s from Tekton Results: %v\n", tr.Name, err)
+		return b.String()
+	}
+	b.WriteString(logs)
+	if logs != "" && !strings.HasSuffix(logs, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,283 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/openshift-pipelines/mcp-tekton/internal/resultsfake"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	fakepipelinerun "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	rtesting "knative.dev/pkg/reconciler/testing"
+)
+
+// setupResults returns a context with fake informers and a client of a fake Tekton Results server
+// archiving:
+//   - the PipelineRun build-1, deleted from the cluster, with its TaskRun build-1-compile and its logs
+//   - the PipelineRun build-2, still in the cluster
+func setupResults(t *testing.T) (context.Context, *resultsfake.Server) {
+	t.Helper()
+	ctx, _ := rtesting.SetupFakeContext(t)
+	s := resultsfake.NewServer()
+	t.Cleanup(s.Close)
+	c, err := NewResultsClient(s.URL, "", &rest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = WithResults(ctx, c)
+
+	archived := &v1.PipelineRun{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1", Kind: "PipelineRun"},
+		ObjectMeta: metav1.ObjectMeta{Name: "build-1", Namespace: "default", UID: "uid-build-1", Labels: map[string]string{"app": "web"}},
+	}
+	tr := &v1.TaskRun{
+		TypeMeta: metav1.TypeMeta{APIVersion: "tekton.dev/v1", Kind: "TaskRun"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "build-1-compile", Namespace: "default", UID: "uid-build-1-compile",
+			Labels:          map[string]string{pipeline.PipelineRunLabelKey: "build-1", pipeline.PipelineTaskLabelKey: "compile"},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(archived, v1.SchemeGroupVersion.WithKind("PipelineRun"))},
+		},
+	}
+	inCluster := &v1.PipelineRun{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1", Kind: "PipelineRun"},
+		ObjectMeta: metav1.ObjectMeta{Name: "build-2", Namespace: "default", UID: "uid-build-2", Labels: map[string]string{"app": "web"}},
+	}
+	logs := "compiling\ndone\n"
+	for _, r := range []struct {
+		dataType string
+		obj      any
+		logs     *string
+	}{
+		{"tekton.dev/v1.PipelineRun", archived, nil},
+		{"tekton.dev/v1.TaskRun", tr, &logs},
+		{"tekton.dev/v1.PipelineRun", inCluster, nil},
+	} {
+		if _, err := s.AddRecord("default", r.dataType, r.obj, r.logs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fakepipelinerun.Get(ctx).Informer().GetIndexer().Add(inCluster); err != nil {
+		t.Fatal(err)
+	}
+	return ctx, s
+}
+
+func TestListArchived(t *testing.T) {
+	ctx, _ := setupResults(t)
+	k, _ := listKindOf("pipelinerun")
+	cached, err := listCached(k.informer(ctx), "default", labels.Everything())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		prefix   string
+		selector string
+		want     []string
+	}{
+		{name: "runs missing from the cluster", want: []string{"build-1"}},
+		{name: "prefix", prefix: "build-1", want: []string{"build-1"}},
+		{name: "other prefix", prefix: "deploy"},
+		{name: "selector", selector: "app=web", want: []string{"build-1"}},
+		{name: "other selector", selector: "app=api"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selector, err := labels.Parse(test.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			archived, err := listArchived(ctx, k, "default", test.prefix, selector, cached)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, obj := range archived.objs {
+				names = append(names, obj.GetName())
+				if archived.records[obj.GetUID()] == "" {
+					t.Errorf("no record for %s", obj.GetName())
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(test.want, ",") {
+				t.Errorf("got %v, want %v", names, test.want)
+			}
+		})
+	}
+
+	// Results only archives runs
+	k, _ = listKindOf("customrun")
+	if archived, err := listArchived(ctx, k, "default", "", labels.Everything(), nil); err != nil || len(archived.objs) != 0 {
+		t.Errorf("got %v, %v for CustomRuns, want nothing", archived.objs, err)
+	}
+}
+
+func TestGetArchived(t *testing.T) {
+	ctx, _ := setupResults(t)
+	k, _ := listKindOf("pipelinerun")
+
+	obj, record, err := getArchived(ctx, k, "default", "build-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, ok := obj.(*v1.PipelineRun)
+	if !ok || pr.UID != types.UID("uid-build-1") {
+		t.Errorf("got %#v, want PipelineRun build-1", obj)
+	}
+	if !strings.HasPrefix(record, "default/results/") {
+		t.Errorf("got record %q", record)
+	}
+
+	if obj, _, err := getArchived(ctx, k, "default", "missing"); obj != nil || err != nil {
+		t.Errorf("got %v, %v for a missing run, want nothing", obj, err)
+	}
+	if obj, _, err := getArchived(ctx, k, "other", "build-1"); obj != nil || err != nil {
+		t.Errorf("got %v, %v in another namespace, want nothing", obj, err)
+	}
+	if obj, _, err := getArchived(context.Background(), k, "default", "build-1"); obj != nil || err != nil {
+		t.Errorf("got %v, %v without Results, want nothing", obj, err)
+	}
+}
+
+func TestArchivedLogs(t *testing.T) {
+	ctx, _ := setupResults(t)
+	one := int64(1)
+
+	tests := []struct {
+		name     string
+		kind     string
+		run      string
+		opts     logOptions
+		found    bool
+		contains []string
+	}{{
+		name:     "taskrun",
+		kind:     "taskrun",
+		run:      "build-1-compile",
+		found:    true,
+		contains: []string{"=== TaskRun build-1-compile (from Tekton Results, record default/results/", "compiling\ndone\n"},
+	}, {
+		name:     "pipelinerun",
+		kind:     "pipelinerun",
+		run:      "build-1",
+		found:    true,
+		contains: []string{"##### Task compile #####\n", "compiling\ndone\n"},
+	}, {
+		name:     "tail lines",
+		kind:     "taskrun",
+		run:      "build-1-compile",
+		opts:     logOptions{tailLines: &one},
+		found:    true,
+		contains: []string{"===\ndone\n"},
+	}, {
+		name:     "limit bytes",
+		kind:     "taskrun",
+		run:      "build-1-compile",
+		opts:     logOptions{limitBytes: 5},
+		found:    true,
+		contains: []string{"===\n[... 10 bytes truncated ...]\ndone\n"},
+	}, {
+		name:     "pipelinerun without taskruns",
+		kind:     "pipelinerun",
+		run:      "build-2",
+		found:    true,
+		contains: []string{"Tekton Results has no TaskRuns of PipelineRun build-2"},
+	}, {
+		name: "missing",
+		kind: "taskrun",
+		run:  "missing",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			k, _ := listKindOf(test.kind)
+			logs, found, err := archivedLogs(ctx, k, "default", test.run, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != test.found {
+				t.Fatalf("found = %v, want %v", found, test.found)
+			}
+			for _, s := range test.contains {
+				if !strings.Contains(logs, s) {
+					t.Errorf("logs %q don't contain %q", logs, s)
+				}
+			}
+		})
+	}
+}
+
+func TestListPipelineRunsFromResults(t *testing.T) {
+	ctx, _ := setupResults(t)
+	k, _ := listKindOf("pipelinerun")
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"namespace": "default"}
+	result, err := k.handlerList()(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if len(result.Content) != 2 {
+		t.Fatalf("got %d contents, want the list and the archived note: %v", len(result.Content), result.Content)
+	}
+
+	list := result.Content[0].(mcp.TextContent).Text
+	for _, s := range []string{`"name":"build-1"`, `"name":"build-2"`, `"source":"results"`} {
+		if !strings.Contains(list, s) {
+			t.Errorf("list %s doesn't contain %s", list, s)
+		}
+	}
+	if strings.Count(list, `"source":"results"`) != 1 {
+		t.Errorf("list %s should only mark build-1 as read from Results", list)
+	}
+	note := result.Content[1].(mcp.TextContent).Text
+	if !strings.HasPrefix(note, "1 objects are no longer in the cluster and were read from Tekton Results: default/build-1 (record default/results/") {
+		t.Errorf("got note %q", note)
+	}
+}
+
+func TestResourceFromResults(t *testing.T) {
+	ctx, _ := setupResults(t)
+
+	tests := []struct {
+		name     string
+		run      string
+		mimeType string
+	}{
+		{name: "in the cluster", run: "build-2", mimeType: "application/json;type=pipelinerun"},
+		{name: "archived", run: "build-1", mimeType: "application/json;type=pipelinerun;source=results"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := mcp.ReadResourceRequest{}
+			request.Params.URI = TektonResourceURI("pipelinerun", "default", test.run)
+			request.Params.Arguments = map[string]any{"namespace": []string{"default"}, "name": []string{test.run}}
+			contents, err := TektonResourceContentHandler(ctx)(ctx, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := contents[0].(mcp.TextResourceContents)
+			if text.MIMEType != test.mimeType {
+				t.Errorf("got MIME type %q, want %q", text.MIMEType, test.mimeType)
+			}
+			if !strings.Contains(text.Text, `"name":"`+test.run+`"`) {
+				t.Errorf("got %s", text.Text)
+			}
+		})
+	}
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = TektonResourceURI("pipelinerun", "default", "missing")
+	request.Params.Arguments = map[string]any{"namespace": []string{"default"}, "name": []string{"missing"}}
+	if _, err := TektonResourceContentHandler(ctx)(ctx, request); err == nil {
+		t.Error("expected an error for a run neither in the cluster nor in Results")
+	}
+}
@@ -0,0 +1,193 @@
+// Package resultsfake serves an in-memory Tekton Results REST API, to exercise the Results
+// integration of the server without a Results deployment.
+package resultsfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/uuid"
+)
+
+// Server is a fake Tekton Results API server, serving the records and logs added to it. It
+// supports the record filters, pagination and the create_time desc order used by the server.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	records []record
+	env     *cel.Env
+}
+
+type record struct {
+	Name string     `json:"name"`
+	UID  string     `json:"uid"`
+	Data recordData `json:"data"`
+
+	namespace string
+	logs      *string
+}
+
+type recordData struct {
+	Type  string `json:"type"`
+	Value []byte `json:"value"`
+}
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewServer starts a fake Tekton Results API server, to be closed by the caller.
+func NewServer() *Server {
+	env, err := cel.NewEnv(
+		cel.Variable("data_type", cel.StringType),
+		cel.Variable("data", cel.DynType),
+	)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{env: env}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /apis/results.tekton.dev/v1alpha2/parents/{parent}/results/{result}/records", s.listRecords)
+	mux.HandleFunc("GET /apis/results.tekton.dev/v1alpha3/parents/{parent}/results/{result}/logs/{record}", s.getLog)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddRecord archives an object of the given type (e.g. tekton.dev/v1.PipelineRun) in a namespace, with
+// the logs of the run if logs is not nil, and returns the name of its record. The records added last
+// are the most recent ones.
+func (s *Server) AddRecord(namespace, dataType string, obj any, logs *string) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	uid := uuid.NewString()
+	r := record{
+		Name:      fmt.Sprintf("%s/results/%s/records/%s", namespace, uuid.NewString(), uid),
+		UID:       uid,
+		Data:      recordData{Type: dataType, Value: data},
+		namespace: namespace,
+		logs:      logs,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, r)
+	return r.Name, nil
+}
+
+func (s *Server) listRecords(w http.ResponseWriter, req *http.Request) {
+	parent, result := req.PathValue("parent"), req.PathValue("result")
+	query := req.URL.Query()
+	if order := query.Get("order_by"); order != "" && order != "create_time desc" {
+		writeError(w, http.StatusBadRequest, 3, fmt.Sprintf("unsupported order_by %q", order))
+		return
+	}
+	pageSize, offset := 50, 0
+	if size := query.Get("page_size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, 3, fmt.Sprintf("invalid page_size %q", size))
+			return
+		}
+		if n > 0 {
+			pageSize = n
+		}
+	}
+	if token := query.Get("page_token"); token != "" {
+		n, err := strconv.Atoi(token)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, 3, fmt.Sprintf("invalid page_token %q", token))
+			return
+		}
+		offset = n
+	}
+
+	var program cel.Program
+	if filter := query.Get("filter"); filter != "" {
+		ast, issues := s.env.Compile(filter)
+		if issues != nil && issues.Err() != nil {
+			writeError(w, http.StatusBadRequest, 3, fmt.Sprintf("invalid filter: %v", issues.Err()))
+			return
+		}
+		var err error
+		if program, err = s.env.Program(ast); err != nil {
+			writeError(w, http.StatusBadRequest, 3, fmt.Sprintf("invalid filter: %v", err))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	records := slices.Clone(s.records)
+	s.mu.Unlock()
+	slices.Reverse(records)
+
+	var matched []record
+	for _, r := range records {
+		if parent != "-" && r.namespace != parent {
+			continue
+		}
+		if result != "-" && !strings.HasPrefix(r.Name, fmt.Sprintf("%s/results/%s/", r.namespace, result)) {
+			continue
+		}
+		if program != nil {
+			var data any
+			_ = json.Unmarshal(r.Data.Value, &data)
+			out, _, err := program.Eval(map[string]any{"data_type": r.Data.Type, "data": data})
+			if err != nil {
+				// Like a SQL query, records missing the filtered fields don't match
+				continue
+			}
+			if out != types.True {
+				continue
+			}
+		}
+		matched = append(matched, r)
+	}
+
+	response := struct {
+		Records       []record `json:"records"`
+		NextPageToken string   `json:"nextPageToken"`
+	}{Records: []record{}}
+	if offset < len(matched) {
+		end := min(offset+pageSize, len(matched))
+		response.Records = matched[offset:end]
+		if end < len(matched) {
+			response.NextPageToken = strconv.Itoa(end)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) getLog(w http.ResponseWriter, req *http.Request) {
+	name := fmt.Sprintf("%s/results/%s/records/%s", req.PathValue("parent"), req.PathValue("result"), req.PathValue("record"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.records {
+		if r.Name == name && r.logs != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(*r.logs))
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, 5, fmt.Sprintf("no logs for record %s", name))
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(apiError{Code: code, Message: message})
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+// runStatuses are the values of the status filter of the run list tools.
+var runStatuses = []string{"succeeded", "failed", "running", "pending", "cancelled", "timed-out"}
+
+var (
+	// cancelledReasons are the reasons of the runs that were cancelled or stopped.
+	cancelledReasons = []string{
+		v1.PipelineRunReasonCancelled.String(),
+		v1.PipelineRunReasonCancelledRunningFinally.String(),
+		v1.PipelineRunReasonStoppedRunningFinally.String(),
+		v1.TaskRunReasonCancelled.String(),
+		v1beta1.CustomRunReasonCancelled.String(),
+	}
+	// timedOutReasons are the reasons of the runs that timed out.
+	timedOutReasons = []string{
+		v1.PipelineRunReasonTimedOut.String(),
+		v1.TaskRunReasonTimedOut.String(),
+		v1beta1.CustomRunReasonTimedOut.String(),
+	}
+)
+
+// runFilter selects PipelineRuns, TaskRuns or CustomRuns by status, reference and time window. The zero
+// runFilter matches every object.
+type runFilter struct {
+	status         string
+	reason         string
+	ref            string
+	startedAfter   *time.Time
+	finishedBefore *time.Time
+	minDuration    time.Duration
+}
+
+// runFilterToolOptions returns the filter arguments of a run list tool, refKind is the kind
+// referenced by the runs (Pipeline, Task or custom task kind).
+func runFilterToolOptions(refArg, refKind string) []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString("status",
+			mcp.Description("Only list the runs with this status: succeeded, failed (any run that did not succeed, including cancelled and timed out), "+
+				"running, pending (not started yet), cancelled (cancelled or stopped) or timed-out"),
+			mcp.Enum(runStatuses...),
+		),
+		mcp.WithString("reason",
+			mcp.Description("Only list the runs whose Succeeded condition has this reason (e.g. Failed, PipelineRunTimeout)"),
+		),
+		mcp.WithString(refArg,
+			mcp.Description(fmt.Sprintf("Only list the runs of this %s", refKind)),
+		),
+		mcp.WithString("started-after",
+			mcp.Description("Only list the runs started after this time, as RFC3339 or as a duration before now (e.g. 24h)"),
+		),
+		mcp.WithString("finished-before",
+			mcp.Description("Only list the runs finished before this time, as RFC3339 or as a duration before now (e.g. 1h)"),
+		),
+		mcp.WithString("min-duration",
+			mcp.Description("Only list the runs that ran for at least this duration (e.g. 10m), up to now for the runs still running"),
+		),
+	}
+}
+
+// runFilterParams reads the filter arguments of a run list tool.
+func runFilterParams(request mcp.CallToolRequest, refArg string) (runFilter, error) {
+	var f runFilter
+	var err error
+
+	if f.status, err = OptionalParam[string](request, "status"); err != nil {
+		return f, err
+	}
+	if f.status != "" && !slices.Contains(runStatuses, f.status) {
+		return f, fmt.Errorf("parameter status must be one of %v", runStatuses)
+	}
+	if f.reason, err = OptionalParam[string](request, "reason"); err != nil {
+		return f, err
+	}
+	if f.ref, err = OptionalParam[string](request, refArg); err != nil {
+		return f, err
+	}
+	if f.startedAfter, err = timeParam(request, "started-after"); err != nil {
+		return f, err
+	}
+	if f.finishedBefore, err = timeParam(request, "finished-before"); err != nil {
+		return f, err
+	}
+
+	minDuration, err := OptionalParam[string](request, "min-duration")
+	if err != nil {
+		return f, err
+	}
+	if minDuration != "" {
+		if f.minDuration, err = time.ParseDuration(minDuration); err != nil {
+			return f, fmt.Errorf("parameter min-duration is not a valid duration: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// timeParam reads a time argument, given as RFC3339 or as a duration before now.
+func timeParam(request mcp.CallToolRequest, p string) (*time.Time, error) {
+	value, err := OptionalParam[string](request, p)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("parameter %s must be a RFC3339 time or a duration, not %q", p, value)
+	}
+	t := time.Now().Add(-d)
+	return &t, nil
+}
+
+// runFields are the fields of a PipelineRun, TaskRun or CustomRun the runs are filtered and summarized by.
+type runFields struct {
+	condition, reason string
+	ref               string
+	start, completion *metav1.Time
+	pending           bool
+}
+
+// runFieldsOf returns the fields of a PipelineRun, TaskRun or CustomRun, false for other objects.
+func runFieldsOf(obj metav1.Object) (runFields, bool) {
+	var f runFields
+	var status duckv1.Status
+	switch run := obj.(type) {
+	case *v1.PipelineRun:
+		status, f.start, f.completion = run.Status.Status, run.Status.StartTime, run.Status.CompletionTime
+		f.ref = pipelineRefName(run.Spec.PipelineRef, run.Labels[pipeline.PipelineLabelKey])
+		f.pending = run.IsPending()
+	case *v1.TaskRun:
+		status, f.start, f.completion = run.Status.Status, run.Status.StartTime, run.Status.CompletionTime
+		f.ref = taskRefName(run.Spec.TaskRef, run.Labels[pipeline.TaskLabelKey])
+	case *v1beta1.CustomRun:
+		status, f.start, f.completion = run.Status.Status, run.Status.StartTime, run.Status.CompletionTime
+		f.ref = customTaskKind(run.Spec)
+	default:
+		return f, false
+	}
+	f.condition, f.reason, _ = succeededCondition(status)
+	f.pending = f.pending || f.start == nil
+	return f, true
+}
+
+// state returns the status of the run, one of runStatuses. The failed state excludes the runs that
+// were cancelled or timed out.
+func (r runFields) state() string {
+	switch {
+	case r.condition == string(corev1.ConditionTrue):
+		return "succeeded"
+	case r.condition == string(corev1.ConditionFalse) && slices.Contains(cancelledReasons, r.reason):
+		return "cancelled"
+	case r.condition == string(corev1.ConditionFalse) && slices.Contains(timedOutReasons, r.reason):
+		return "timed-out"
+	case r.condition == string(corev1.ConditionFalse):
+		return "failed"
+	case r.pending && r.completion == nil:
+		return "pending"
+	}
+	return "running"
+}
+
+// matches returns true if the object is selected by the filter. Objects that are not runs only
+// match the zero filter.
+func (f runFilter) matches(obj metav1.Object) bool {
+	if f == (runFilter{}) {
+		return true
+	}
+
+	run, ok := runFieldsOf(obj)
+	if !ok {
+		return false
+	}
+
+	if f.status != "" {
+		// failed also selects the runs that were cancelled or timed out
+		failed := f.status == "failed" && run.condition == string(corev1.ConditionFalse)
+		if !failed && run.state() != f.status {
+			return false
+		}
+	}
+	if f.reason != "" && run.reason != f.reason {
+		return false
+	}
+	if f.ref != "" && run.ref != f.ref {
+		return false
+	}
+	if f.startedAfter != nil && (run.start == nil || !run.start.Time.After(*f.startedAfter)) {
+		return false
+	}
+	if f.finishedBefore != nil && (run.completion == nil || !run.completion.Time.Before(*f.finishedBefore)) {
+		return false
+	}
+	if f.minDuration > 0 {
+		if run.start == nil {
+			return false
+		}
+		end := time.Now()
+		if run.completion != nil {
+			end = run.completion.Time
+		}
+		if end.Sub(run.start.Time) < f.minDuration {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+func TestRunFilterMatches(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(base.Add(d))
+		return &t
+	}
+	condition := func(status corev1.ConditionStatus, reason string) duckv1.Status {
+		return duckv1.Status{Conditions: duckv1.Conditions{{Type: apis.ConditionSucceeded, Status: status, Reason: reason}}}
+	}
+	pipelineRun := func(status duckv1.Status, start, completion *metav1.Time) *v1.PipelineRun {
+		pr := &v1.PipelineRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-x7k2", Namespace: "default"},
+			Spec:       v1.PipelineRunSpec{PipelineRef: &v1.PipelineRef{Name: "build"}},
+		}
+		pr.Status.Status, pr.Status.StartTime, pr.Status.CompletionTime = status, start, completion
+		return pr
+	}
+
+	succeeded := pipelineRun(condition(corev1.ConditionTrue, "Succeeded"), at(0), at(10*time.Minute))
+	failed := pipelineRun(condition(corev1.ConditionFalse, "Failed"), at(0), at(time.Minute))
+	cancelled := pipelineRun(condition(corev1.ConditionFalse, v1.PipelineRunReasonCancelled.String()), at(0), at(time.Minute))
+	timedOut := pipelineRun(condition(corev1.ConditionFalse, v1.PipelineRunReasonTimedOut.String()), at(0), at(time.Hour))
+	running := pipelineRun(condition(corev1.ConditionUnknown, "Running"), at(-time.Hour), nil)
+	pending := pipelineRun(duckv1.Status{}, nil, nil)
+	pending.Spec.Status = v1.PipelineRunSpecStatusPending
+	taskRun := &v1.TaskRun{Spec: v1.TaskRunSpec{TaskRef: &v1.TaskRef{Name: "compile"}}}
+	taskRun.Status.Status, taskRun.Status.StartTime = condition(corev1.ConditionTrue, "Succeeded"), at(0)
+	customRun := &v1beta1.CustomRun{Spec: v1beta1.CustomRunSpec{CustomRef: &v1beta1.TaskRef{APIVersion: "example.dev/v1", Kind: "Wait"}}}
+	customRun.Status.Status = condition(corev1.ConditionFalse, v1beta1.CustomRunReasonCancelled.String())
+	pipeline := &v1.Pipeline{ObjectMeta: metav1.ObjectMeta{Name: "build"}}
+
+	tests := []struct {
+		name   string
+		filter runFilter
+		obj    metav1.Object
+		want   bool
+	}{
+		{name: "zero filter matches runs", obj: running, want: true},
+		{name: "zero filter matches other objects", obj: pipeline, want: true},
+		{name: "other objects don't match a filter", filter: runFilter{status: "succeeded"}, obj: pipeline},
+		{name: "succeeded", filter: runFilter{status: "succeeded"}, obj: succeeded, want: true},
+		{name: "not succeeded", filter: runFilter{status: "succeeded"}, obj: failed},
+		{name: "failed", filter: runFilter{status: "failed"}, obj: failed, want: true},
+		{name: "failed includes cancelled", filter: runFilter{status: "failed"}, obj: cancelled, want: true},
+		{name: "failed includes timed out", filter: runFilter{status: "failed"}, obj: timedOut, want: true},
+		{name: "cancelled", filter: runFilter{status: "cancelled"}, obj: cancelled, want: true},
+		{name: "cancelled excludes failed", filter: runFilter{status: "cancelled"}, obj: failed},
+		{name: "timed out", filter: runFilter{status: "timed-out"}, obj: timedOut, want: true},
+		{name: "running", filter: runFilter{status: "running"}, obj: running, want: true},
+		{name: "pending", filter: runFilter{status: "pending"}, obj: pending, want: true},
+		{name: "pending is not running", filter: runFilter{status: "running"}, obj: pending},
+		{name: "cancelled CustomRun", filter: runFilter{status: "cancelled"}, obj: customRun, want: true},
+		{name: "reason", filter: runFilter{reason: "Failed"}, obj: failed, want: true},
+		{name: "other reason", filter: runFilter{reason: "Failed"}, obj: cancelled},
+		{name: "pipeline", filter: runFilter{ref: "build"}, obj: succeeded, want: true},
+		{name: "other pipeline", filter: runFilter{ref: "deploy"}, obj: succeeded},
+		{name: "task", filter: runFilter{ref: "compile"}, obj: taskRun, want: true},
+		{name: "custom task kind", filter: runFilter{ref: "Wait"}, obj: customRun, want: true},
+		{name: "started after", filter: runFilter{startedAfter: &at(-time.Minute).Time}, obj: succeeded, want: true},
+		{name: "started before", filter: runFilter{startedAfter: &at(time.Minute).Time}, obj: succeeded},
+		{name: "not started", filter: runFilter{startedAfter: &at(-time.Minute).Time}, obj: pending},
+		{name: "finished before", filter: runFilter{finishedBefore: &at(time.Hour).Time}, obj: succeeded, want: true},
+		{name: "finished after", filter: runFilter{finishedBefore: &at(5 * time.Minute).Time}, obj: succeeded},
+		{name: "not finished", filter: runFilter{finishedBefore: &at(time.Hour).Time}, obj: running},
+		{name: "long enough", filter: runFilter{minDuration: 10 * time.Minute}, obj: succeeded, want: true},
+		{name: "too short", filter: runFilter{minDuration: 10 * time.Minute}, obj: failed},
+		{name: "running for long enough", filter: runFilter{minDuration: 10 * time.Minute}, obj: running, want: true},
+		{name: "all conditions", filter: runFilter{status: "failed", reason: "Failed", ref: "build", minDuration: time.Minute}, obj: failed, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.matches(test.obj); got != test.want {
+				t.Errorf("matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// workspaceDeclaration is the common part of Pipeline and Task workspace declarations.
+type workspaceDeclaration struct {
+	Name     string
+	Optional bool
+}
+
+func pipelineWorkspaceDeclarations(ws []v1.PipelineWorkspaceDeclaration) []workspaceDeclaration {
+	decls := make([]workspaceDeclaration, 0, len(ws))
+	for _, w := range ws {
+		decls = append(decls, workspaceDeclaration{Name: w.Name, Optional: w.Optional})
+	}
+	return decls
+}
+
+func taskWorkspaceDeclarations(ws []v1.WorkspaceDeclaration) []workspaceDeclaration {
+	decls := make([]workspaceDeclaration, 0, len(ws))
+	for _, w := range ws {
+		decls = append(decls, workspaceDeclaration{Name: w.Name, Optional: w.Optional})
+	}
+	return decls
+}
+
+// parseRunParams converts the "params" object of a tool call into Tekton params.
+// Values can be strings (numbers and booleans are converted to strings), arrays of strings
+// or objects with string values.
+func parseRunParams(request mcp.CallToolRequest) (v1.Params, []string) {
+	raw, err := OptionalParam[map[string]any](request, "params")
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params v1.Params
+	var errs []string
+	for _, name := range names {
+		switch value := raw[name].(type) {
+		case []any:
+			values := make([]string, 0, len(value))
+			for i, item := range value {
+				s, ok := scalarString(item)
+				if !ok {
+					errs = append(errs, fmt.Sprintf("params.%s[%d]: must be a string, is %T", name, i, item))
+					continue
+				}
+				values = append(values, s)
+			}
+			params = append(params, v1.Param{Name: name, Value: v1.ParamValue{Type: v1.ParamTypeArray, ArrayVal: values}})
+		case map[string]any:
+			values := make(map[string]string, len(value))
+			for key, item := range value {
+				s, ok := scalarString(item)
+				if !ok {
+					errs = append(errs, fmt.Sprintf("params.%s.%s: must be a string, is %T", name, key, item))
+					continue
+				}
+				values[key] = s
+			}
+			params = append(params, v1.Param{Name: name, Value: *v1.NewObject(values)})
+		default:
+			s, ok := scalarString(value)
+			if !ok {
+				errs = append(errs, fmt.Sprintf("params.%s: must be a string, an array of strings or an object, is %T", name, value))
+				continue
+			}
+			params = append(params, v1.Param{Name: name, Value: *v1.NewStructuredValues(s)})
+		}
+	}
+	return params, errs
+}
+
+func scalarString(v any) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(s), true
+	default:
+		return "", false
+	}
+}
+
+// validateRunParams checks the given params against the params declared by a Pipeline or a Task:
+// unknown params, missing required params, types, enums and object keys.
+func validateRunParams(specs v1.ParamSpecs, params v1.Params) []string {
+	var errs []string
+
+	declared := make(map[string]v1.ParamSpec, len(specs))
+	for _, spec := range specs {
+		declared[spec.Name] = spec
+	}
+
+	provided := make(map[string]v1.ParamValue, len(params))
+	for _, p := range params {
+		provided[p.Name] = p.Value
+		if _, ok := declared[p.Name]; !ok {
+			errs = append(errs, fmt.Sprintf("params.%s: not declared (declared params: %s)", p.Name, strings.Join(specs.GetNames(), ", ")))
+		}
+	}
+
+	for _, spec := range specs {
+		value, ok := provided[spec.Name]
+		if !ok {
+			if spec.Default == nil {
+				errs = append(errs, fmt.Sprintf("params.%s: required %s param is missing", spec.Name, paramSpecType(spec)))
+			}
+			continue
+		}
+
+		expected := paramSpecType(spec)
+		if value.Type != expected {
+			errs = append(errs, fmt.Sprintf("params.%s: must be of type %s, is %s", spec.Name, expected, value.Type))
+			continue
+		}
+
+		if len(spec.Enum) > 0 && expected == v1.ParamTypeString && !slices.Contains(spec.Enum, value.StringVal) {
+			errs = append(errs, fmt.Sprintf("params.%s: %q is not one of the allowed values: %s", spec.Name, value.StringVal, strings.Join(spec.Enum, ", ")))
+		}
+
+		if expected == v1.ParamTypeObject {
+			keys := make([]string, 0, len(spec.Properties))
+			for key := range spec.Properties {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if _, ok := value.ObjectVal[key]; ok {
+					continue
+				}
+				if spec.Default != nil {
+					if _, ok := spec.Default.ObjectVal[key]; ok {
+						continue
+					}
+				}
+				errs = append(errs, fmt.Sprintf("params.%s.%s: required key is missing", spec.Name, key))
+			}
+		}
+	}
+
+	return errs
+}
+
+// paramSpecType returns the effective type of a ParamSpec, which defaults to
+// the type of its default value, or string.
+func paramSpecType(spec v1.ParamSpec) v1.ParamType {
+	if spec.Type != "" {
+		return spec.Type
+	}
+	if spec.Default != nil && spec.Default.Type != "" {
+		return spec.Default.Type
+	}
+	return v1.ParamTypeString
+}
+
+// validateRunWorkspaces checks the given workspace bindings against the workspaces declared
+// by a Pipeline or a Task.
+func validateRunWorkspaces(decls []workspaceDeclaration, bindings []v1.WorkspaceBinding) []string {
+	var errs []string
+
+	declared := make(map[string]bool, len(decls))
+	names := make([]string, 0, len(decls))
+	for _, d := range decls {
+		declared[d.Name] = true
+		names = append(names, d.Name)
+	}
+
+	bound := make(map[string]bool, len(bindings))
+	for i, b := range bindings {
+		if b.Name == "" {
+			errs = append(errs, fmt.Sprintf("workspaces[%d].name: is required", i))
+			continue
+		}
+		if bound[b.Name] {
+			errs = append(errs, fmt.Sprintf("workspaces[%d]: workspace %s is bound more than once", i, b.Name))
+		}
+		bound[b.Name] = true
+		if !declared[b.Name] {
+			errs = append(errs, fmt.Sprintf("workspaces[%d]: workspace %s is not declared (declared workspaces: %s)", i, b.Name, strings.Join(names, ", ")))
+		}
+		if n := workspaceSourceCount(b); n != 1 {
+			errs = append(errs, fmt.Sprintf("workspaces[%d]: workspace %s must have exactly one of persistentVolumeClaim, volumeClaimTemplate, emptyDir, configMap, secret, csi or projected, has %d", i, b.Name, n))
+		}
+	}
+
+	for _, d := range decls {
+		if !d.Optional && !bound[d.Name] {
+			errs = append(errs, fmt.Sprintf("workspaces: required workspace %s is not bound", d.Name))
+		}
+	}
+
+	return errs
+}
+
+func workspaceSourceCount(b v1.WorkspaceBinding) int {
+	n := 0
+	if b.PersistentVolumeClaim != nil {
+		n++
+	}
+	if b.VolumeClaimTemplate != nil {
+		n++
+	}
+	if b.EmptyDir != nil {
+		n++
+	}
+	if b.ConfigMap != nil {
+		n++
+	}
+	if b.Secret != nil {
+		n++
+	}
+	if b.CSI != nil {
+		n++
+	}
+	if b.Projected != nil {
+		n++
+	}
+	return n
+}
+
+// validateStepSpecs checks that step and sidecar overrides target steps and sidecars declared by the Task.
+func validateStepSpecs(spec v1.TaskSpec, stepSpecs []v1.TaskRunStepSpec, sidecarSpecs []v1.TaskRunSidecarSpec) []string {
+	var errs []string
+
+	steps := make([]string, 0, len(spec.Steps))
+	for _, step := range spec.Steps {
+		steps = append(steps, step.Name)
+	}
+	for i, s := range stepSpecs {
+		if !slices.Contains(steps, s.Name) {
+			errs = append(errs, fmt.Sprintf("step-specs[%d]: step %q is not declared (declared steps: %s)", i, s.Name, strings.Join(steps, ", ")))
+		}
+	}
+
+	sidecars := make([]string, 0, len(spec.Sidecars))
+	for _, sidecar := range spec.Sidecars {
+		sidecars = append(sidecars, sidecar.Name)
+	}
+	for i, s := range sidecarSpecs {
+		if !slices.Contains(sidecars, s.Name) {
+			errs = append(errs, fmt.Sprintf("sidecar-specs[%d]: sidecar %q is not declared (declared sidecars: %s)", i, s.Name, strings.Join(sidecars, ", ")))
+		}
+	}
+
+	return errs
+}
+
+// invalidRunError builds the tool error returned when the input of a start tool doesn't match
+// the interface declared by the Pipeline or Task.
+func invalidRunError(kind, namespace, name string, errs []string) *mcp.CallToolResult {
+	return mcpError(fmt.Sprintf("Invalid input for %s %s/%s:\n- %s", kind, namespace, name, strings.Join(errs, "\n- ")))
+}
+
+// workspaceBindingSchema is the JSON schema of the items of the "workspaces" tool parameter.
+var workspaceBindingSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"name":    map[string]any{"type": "string", "description": "Name of the declared workspace"},
+		"subPath": map[string]any{"type": "string", "description": "Directory on the volume to use as the workspace"},
+		"persistentVolumeClaim": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"claimName": map[string]any{"type": "string"}},
+		},
+		"volumeClaimTemplate": map[string]any{
+			"type":        "object",
+			"description": "PersistentVolumeClaim created for the run (metadata and spec)",
+		},
+		"emptyDir": map[string]any{"type": "object"},
+		"configMap": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"name": map[string]any{"type": "string"}},
+		},
+		"secret": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"secretName": map[string]any{"type": "string"}},
+		},
+	},
+	"required": []string{"name"},
+}
+
+// resourceOverrideSchema is the JSON schema of the items of the "step-specs" and "sidecar-specs" tool parameters.
+var resourceOverrideSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"name": map[string]any{"type": "string", "description": "Name of the step or sidecar to override"},
+		"computeResources": map[string]any{
+			"type":        "object",
+			"description": "Compute resources (requests and limits), using the Kubernetes ResourceRequirements format",
+		},
+	},
+	"required": []string{"name", "computeResources"},
+}
+
+// runResult builds the result of a start tool: a summary of the run, optional details and the run
+// itself as an embedded tekton:// resource, so that the client can read or subscribe to it.
+func runResult(resourceType string, run metav1.Object, summary string, details any) (*mcp.CallToolResult, error) {
+	uri := TektonResourceURI(resourceType, run.GetNamespace(), run.GetName())
+
+	jsonData, err := json.Marshal(run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
+	}
+
+	content := []mcp.Content{
+		mcp.NewTextContent(fmt.Sprintf("%s: name=%s namespace=%s uid=%s uri=%s", summary, run.GetName(), run.GetNamespace(), run.GetUID(), uri)),
+	}
+	if details != nil {
+		detailsData, err := json.Marshal(details)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal details to JSON: %w", err)
+		}
+		content = append(content, mcp.NewTextContent(string(detailsData)))
+	}
+	content = append(content, mcp.NewEmbeddedResource(jsonResourceContents(resourceType, run.GetNamespace(), run.GetName(), jsonData)))
+
+	return &mcp.CallToolResult{Content: content}, nil
+}
+
+// waitedRunSummary describes how a waited run ended.
+func waitedRunSummary(kind string, outcome runOutcome, done bool) string {
+	if !done {
+		return fmt.Sprintf("%s did not finish before the wait timeout, it is still %s", kind, childState(outcome.Status, outcome.Reason))
+	}
+	return fmt.Sprintf("%s finished: %s", kind, childState(outcome.Status, outcome.Reason))
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateRunParams(t *testing.T) {
+	specs := v1.ParamSpecs{
+		{Name: "url", Type: v1.ParamTypeString},
+		{Name: "revision", Default: v1.NewStructuredValues("main")},
+		{Name: "mode", Enum: []string{"fast", "safe"}, Default: v1.NewStructuredValues("safe")},
+		{Name: "flags", Type: v1.ParamTypeArray, Default: v1.NewStructuredValues("-v")},
+		{Name: "image", Type: v1.ParamTypeObject, Properties: map[string]v1.PropertySpec{"name": {}, "tag": {}}, Default: &v1.ParamValue{
+			Type: v1.ParamTypeObject, ObjectVal: map[string]string{"tag": "latest"},
+		}},
+	}
+
+	tests := []struct {
+		name   string
+		params v1.Params
+		want   []string
+	}{{
+		name: "valid",
+		params: v1.Params{
+			{Name: "url", Value: *v1.NewStructuredValues("https://example.com")},
+			{Name: "mode", Value: *v1.NewStructuredValues("fast")},
+			{Name: "flags", Value: *v1.NewStructuredValues("-v", "-x")},
+			{Name: "image", Value: *v1.NewObject(map[string]string{"name": "app"})},
+		},
+	}, {
+		name: "missing required param",
+		want: []string{"params.url: required string param is missing"},
+	}, {
+		name: "undeclared param",
+		params: v1.Params{
+			{Name: "url", Value: *v1.NewStructuredValues("https://example.com")},
+			{Name: "branch", Value: *v1.NewStructuredValues("main")},
+		},
+		want: []string{"params.branch: not declared (declared params: url, revision, mode, flags, image)"},
+	}, {
+		name: "type defaulting to the type of the default",
+		params: v1.Params{
+			{Name: "url", Value: *v1.NewStructuredValues("https://example.com")},
+			{Name: "flags", Value: *v1.NewStructuredValues("-v")},
+		},
+		want: []string{"params.flags: must be of type array, is string"},
+	}, {
+		name: "value not in the enum",
+		params: v1.Params{
+			{Name: "url", Value: *v1.NewStructuredValues("https://example.com")},
+			{Name: "mode", Value: *v1.NewStructuredValues("slow")},
+		},
+		want: []string{`params.mode: "slow" is not one of the allowed values: fast, safe`},
+	}, {
+		name: "object key missing from the value and the default",
+		params: v1.Params{
+			{Name: "url", Value: *v1.NewStructuredValues("https://example.com")},
+			{Name: "image", Value: *v1.NewObject(map[string]string{"tag": "v1"})},
+		},
+		want: []string{"params.image.name: required key is missing"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := validateRunParams(specs, test.params); !slices.Equal(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateRunWorkspaces(t *testing.T) {
+	decls := []workspaceDeclaration{
+		{Name: "source"},
+		{Name: "cache", Optional: true},
+	}
+	emptyDir := &corev1.EmptyDirVolumeSource{}
+
+	tests := []struct {
+		name     string
+		bindings []v1.WorkspaceBinding
+		want     []string
+	}{{
+		name:     "valid",
+		bindings: []v1.WorkspaceBinding{{Name: "source", EmptyDir: emptyDir}},
+	}, {
+		name: "missing required workspace",
+		want: []string{"workspaces: required workspace source is not bound"},
+	}, {
+		name:     "unnamed binding",
+		bindings: []v1.WorkspaceBinding{{Name: "source", EmptyDir: emptyDir}, {EmptyDir: emptyDir}},
+		want:     []string{"workspaces[1].name: is required"},
+	}, {
+		name:     "undeclared workspace",
+		bindings: []v1.WorkspaceBinding{{Name: "source", EmptyDir: emptyDir}, {Name: "output", EmptyDir: emptyDir}},
+		want:     []string{"workspaces[1]: workspace output is not declared (declared workspaces: source, cache)"},
+	}, {
+		name:     "bound twice",
+		bindings: []v1.WorkspaceBinding{{Name: "source", EmptyDir: emptyDir}, {Name: "source", EmptyDir: emptyDir}},
+		want:     []string{"workspaces[1]: workspace source is bound more than once"},
+	}, {
+		name: "several sources",
+		bindings: []v1.WorkspaceBinding{{
+			Name:     "source",
+			EmptyDir: emptyDir,
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+			},
+		}},
+		want: []string{"workspaces[0]: workspace source must have exactly one of persistentVolumeClaim, volumeClaimTemplate, emptyDir, configMap, secret, csi or projected, has 2"},
+	}, {
+		name:     "no source",
+		bindings: []v1.WorkspaceBinding{{Name: "source"}},
+		want:     []string{"workspaces[0]: workspace source must have exactly one of persistentVolumeClaim, volumeClaimTemplate, emptyDir, configMap, secret, csi or projected, has 0"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := validateRunWorkspaces(decls, test.bindings); !slices.Equal(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
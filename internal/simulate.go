@@ -0,0 +1,554 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/util/jsonpath"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/injection/clients/dynamicclient"
+)
+
+// bindingExpression matches the $(body.x), $(header.x) and $(extensions.x) expressions of the
+// TriggerBinding params.
+var bindingExpression = regexp.MustCompile(`\$\(((?:body|header|extensions)(?:[.\[][^)]*)?)\)`)
+
+func toolSimulateTrigger() mcp.Tool {
+	return mcp.NewTool("simulate_trigger",
+		mcp.WithDescription("Simulate the delivery of an event to an EventListener: evaluate the cel interceptors, TriggerBindings and TriggerTemplates "+
+			"of its triggers in the server, and return the resources each trigger would create, without creating them unless asked to"),
+		mcp.WithString("eventlistener", mcp.Required(),
+			mcp.Description("Name of the EventListener receiving the event"),
+		),
+		mcp.WithString("trigger",
+			mcp.Description("Name of the trigger to simulate, defaults to all the triggers of the EventListener"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace where the EventListener is located, defaults to the server's default namespace"),
+		),
+		mcp.WithString("body",
+			mcp.Description("Body of the event, as JSON"),
+		),
+		mcp.WithObject("headers",
+			mcp.Description("Headers of the event, as an object mapping each header name to a string or an array of strings (e.g. {\"X-GitHub-Event\": \"push\"})"),
+		),
+		mcp.WithBoolean("create",
+			mcp.Description("Create the resources of the triggers that fire, as the EventListener would. "+
+				"PipelineRuns, TaskRuns and CustomRuns are only created if the tool starting them is enabled"),
+		),
+	)
+}
+
+// simulatedTrigger is the outcome of a trigger for the simulated event.
+type simulatedTrigger struct {
+	Trigger    string `json:"trigger"`
+	TriggerURI string `json:"triggerURI,omitempty"`
+	Fired      bool   `json:"fired"`
+	// Reason tells why the trigger didn't fire.
+	Reason       string                 `json:"reason,omitempty"`
+	Interceptors []simulatedInterceptor `json:"interceptors,omitempty"`
+	Extensions   map[string]any         `json:"extensions,omitempty"`
+	Params       map[string]string      `json:"params,omitempty"`
+	Resources    []map[string]any       `json:"resources,omitempty"`
+	Created      []string               `json:"created,omitempty"`
+	Notes        []string               `json:"notes,omitempty"`
+}
+
+// simulatedInterceptor is the outcome of an interceptor for the simulated event.
+type simulatedInterceptor struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Note   string `json:"note,omitempty"`
+}
+
+// namedTrigger is the spec of a trigger of an EventListener, declared inline or in a Trigger.
+type namedTrigger struct {
+	name string
+	uri  string
+	spec map[string]any
+}
+
+func handlerSimulateTrigger(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := OptionalParam[string](request, "eventlistener")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if name == "" {
+		return mcp.NewToolResultError("eventlistener is required"), nil
+	}
+	triggerName, err := OptionalParam[string](request, "trigger")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	create, err := OptionalParam[bool](request, "create")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	event := celEvent{header: http.Header{}}
+	rawBody, err := OptionalParam[string](request, "body")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if rawBody != "" {
+		if err := json.Unmarshal([]byte(rawBody), &event.body); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("body is not valid JSON: %v", err)), nil
+		}
+	}
+	var headers map[string]any
+	if _, err := DecodeParam(request, "headers", &headers); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	for k, v := range headers {
+		switch value := v.(type) {
+		case []any:
+			for _, item := range value {
+				event.header.Add(k, fmt.Sprint(item))
+			}
+		default:
+			event.header.Add(k, fmt.Sprint(value))
+		}
+	}
+
+	el, err := getTriggersObject(ctx, "eventlistener", namespace, name)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to get EventListener %s/%s: %v", namespace, name, err)), nil
+	}
+	triggers, err := eventListenerTriggers(ctx, el, triggerName)
+	if err != nil {
+		return mcpError(err.Error()), nil
+	}
+
+	env, err := newCELEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the CEL environment: %w", err)
+	}
+	eventID := uuid.New().String()
+	var outcomes []simulatedTrigger
+	for _, t := range triggers {
+		outcome := simulateTrigger(ctx, env, el, t, event, eventID)
+		if create && outcome.Fired {
+			outcome.Created, err = createTriggerResources(ctx, outcome.Resources)
+			if err != nil {
+				outcome.Notes = append(outcome.Notes, err.Error())
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	jsonData, err := json.Marshal(outcomes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
+	}
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// getTriggersObject returns an object of a Triggers kind from the informer cache, if the caller is
+// allowed to get it.
+func getTriggersObject(ctx context.Context, resourceType, namespace, name string) (*unstructured.Unstructured, error) {
+	k, _ := listKindOf(resourceType)
+	if err := ConfigFromContext(ctx).CheckNamespace(namespace); err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, "get", k.group, k.resource(), "", namespace, name); err != nil {
+		return nil, err
+	}
+	obj, err := k.getCached(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T in the informer cache", obj)
+	}
+	return u, nil
+}
+
+// eventListenerTriggers returns the triggers of an EventListener, or only the named one.
+func eventListenerTriggers(ctx context.Context, el *unstructured.Unstructured, name string) ([]namedTrigger, error) {
+	items, _, _ := unstructured.NestedSlice(el.Object, "spec", "triggers")
+	var triggers []namedTrigger
+	for _, item := range items {
+		spec, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		triggerName, _, _ := unstructured.NestedString(spec, "name")
+		ref, _, _ := unstructured.NestedString(spec, "triggerRef")
+		if name != "" && name != triggerName && name != ref {
+			continue
+		}
+		if ref == "" {
+			triggers = append(triggers, namedTrigger{name: triggerName, spec: spec})
+			continue
+		}
+		trigger, err := getTriggersObject(ctx, "trigger", el.GetNamespace(), ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Trigger %s/%s: %w", el.GetNamespace(), ref, err)
+		}
+		triggerSpec, _, _ := unstructured.NestedMap(trigger.Object, "spec")
+		triggers = append(triggers, namedTrigger{
+			name: ref,
+			uri:  TektonResourceURI("trigger", trigger.GetNamespace(), trigger.GetName()),
+			spec: triggerSpec,
+		})
+	}
+
+	if len(triggers) == 0 && name != "" {
+		// The EventListener may select Triggers by label instead of listing them
+		trigger, err := getTriggersObject(ctx, "trigger", el.GetNamespace(), name)
+		if err != nil {
+			return nil, fmt.Errorf("trigger %s is not declared by EventListener %s/%s: %w", name, el.GetNamespace(), el.GetName(), err)
+		}
+		triggerSpec, _, _ := unstructured.NestedMap(trigger.Object, "spec")
+		triggers = append(triggers, namedTrigger{
+			name: name,
+			uri:  TektonResourceURI("trigger", trigger.GetNamespace(), trigger.GetName()),
+			spec: triggerSpec,
+		})
+	}
+	if len(triggers) == 0 {
+		return nil, fmt.Errorf("EventListener %s/%s doesn't declare any trigger, its Triggers may be selected by label: name one with the trigger argument", el.GetNamespace(), el.GetName())
+	}
+	return triggers, nil
+}
+
+// simulateTrigger runs the interceptors of a trigger on the event and, if they pass, renders its
+// TriggerTemplate with the params of its TriggerBindings.
+func simulateTrigger(ctx context.Context, env *cel.Env, el *unstructured.Unstructured, t namedTrigger, event celEvent, eventID string) simulatedTrigger {
+	outcome := simulatedTrigger{Trigger: t.name, TriggerURI: t.uri}
+	event.extensions = map[string]any{}
+
+	interceptors, _, _ := unstructured.NestedSlice(t.spec, "interceptors")
+	for _, item := range interceptors {
+		interceptor, _ := item.(map[string]any)
+		result, passed, err := simulateInterceptor(env, interceptor, event)
+		outcome.Interceptors = append(outcome.Interceptors, result)
+		if err != nil {
+			outcome.Reason = fmt.Sprintf("interceptor %s failed: %v", result.Name, err)
+			return outcome
+		}
+		if !passed {
+			outcome.Reason = fmt.Sprintf("interceptor %s filtered the event out", result.Name)
+			return outcome
+		}
+	}
+	if len(event.extensions) > 0 {
+		outcome.Extensions = event.extensions
+	}
+
+	params, notes, err := triggerBindingParams(ctx, el.GetNamespace(), t.spec, event)
+	outcome.Notes = append(outcome.Notes, notes...)
+	if err != nil {
+		outcome.Reason = err.Error()
+		return outcome
+	}
+	outcome.Params = params
+
+	resources, err := renderTriggerTemplate(ctx, el, t, params, eventID)
+	if err != nil {
+		outcome.Reason = err.Error()
+		return outcome
+	}
+	outcome.Resources = resources
+	outcome.Fired = true
+	return outcome
+}
+
+// simulateInterceptor evaluates a cel interceptor, and lets the event through the other
+// interceptors, which run in their own services.
+func simulateInterceptor(env *cel.Env, interceptor map[string]any, event celEvent) (simulatedInterceptor, bool, error) {
+	kind, _, _ := unstructured.NestedString(interceptor, "ref", "name")
+	var filter string
+	var overlays []any
+	if legacy, ok := interceptor["cel"].(map[string]any); ok {
+		kind = "cel"
+		filter, _, _ = unstructured.NestedString(legacy, "filter")
+		overlays, _, _ = unstructured.NestedSlice(legacy, "overlays")
+	}
+	for _, legacy := range []string{"github", "gitlab", "bitbucket", "webhook"} {
+		if _, ok := interceptor[legacy]; ok && kind == "" {
+			kind = legacy
+		}
+	}
+	name, _, _ := unstructured.NestedString(interceptor, "name")
+	if name == "" {
+		name = kind
+	}
+
+	if kind != "cel" {
+		return simulatedInterceptor{
+			Name:   name,
+			Result: "not evaluated",
+			Note:   "only the cel interceptor is evaluated by the simulation, this one is assumed to let the event through",
+		}, true, nil
+	}
+
+	params, _, _ := unstructured.NestedSlice(interceptor, "params")
+	for _, item := range params {
+		param, _ := item.(map[string]any)
+		switch param["name"] {
+		case "filter":
+			filter, _ = param["value"].(string)
+		case "overlays":
+			overlays, _ = param["value"].([]any)
+		}
+	}
+
+	result := simulatedInterceptor{Name: name, Result: "passed"}
+	if filter != "" {
+		value, err := evalCEL(env, filter, event)
+		if err != nil {
+			result.Result = "error"
+			return result, false, err
+		}
+		passed, ok := value.(types.Bool)
+		if !ok {
+			result.Result = "error"
+			return result, false, fmt.Errorf("filter %q returned %s, not a bool", filter, value.Type().TypeName())
+		}
+		if !passed {
+			result.Result = "filtered out"
+			result.Note = fmt.Sprintf("filter %q is false", filter)
+			return result, false, nil
+		}
+	}
+
+	for _, item := range overlays {
+		overlay, _ := item.(map[string]any)
+		key, _ := overlay["key"].(string)
+		expression, _ := overlay["expression"].(string)
+		value, err := evalCEL(env, expression, event)
+		if err != nil {
+			result.Result = "error"
+			return result, false, err
+		}
+		native, err := celNative(value)
+		if err != nil {
+			result.Result = "error"
+			return result, false, err
+		}
+		if err := unstructured.SetNestedField(event.extensions, native, strings.Split(key, ".")...); err != nil {
+			result.Result = "error"
+			return result, false, fmt.Errorf("failed to set overlay %s: %w", key, err)
+		}
+	}
+	return result, true, nil
+}
+
+// triggerBindingParams resolves the params of the TriggerBindings of a trigger on the event.
+func triggerBindingParams(ctx context.Context, namespace string, spec map[string]any, event celEvent) (map[string]string, []string, error) {
+	header := map[string]any{}
+	for k, v := range event.header {
+		header[http.CanonicalHeaderKey(k)] = strings.Join(v, ",")
+	}
+	data := map[string]any{
+		"body":       event.body,
+		"header":     header,
+		"extensions": event.extensions,
+	}
+
+	params := map[string]string{}
+	var notes []string
+	bindings, _, _ := unstructured.NestedSlice(spec, "bindings")
+	for _, item := range bindings {
+		binding, _ := item.(map[string]any)
+		var values []any
+		if name, ok := binding["name"].(string); ok && binding["value"] != nil {
+			values = []any{map[string]any{"name": name, "value": binding["value"]}}
+		} else {
+			ref, _ := binding["ref"].(string)
+			if kind, _ := binding["kind"].(string); kind == "ClusterTriggerBinding" {
+				notes = append(notes, fmt.Sprintf("ClusterTriggerBinding %s is not cached by the server, its params are missing", ref))
+				continue
+			}
+			tb, err := getTriggersObject(ctx, "triggerbinding", namespace, ref)
+			if err != nil {
+				return nil, notes, fmt.Errorf("failed to get TriggerBinding %s/%s: %w", namespace, ref, err)
+			}
+			values, _, _ = unstructured.NestedSlice(tb.Object, "spec", "params")
+		}
+
+		for _, v := range values {
+			param, _ := v.(map[string]any)
+			name, _ := param["name"].(string)
+			value, _ := param["value"].(string)
+			resolved, err := resolveBindingValue(value, data)
+			if err != nil {
+				return nil, notes, fmt.Errorf("failed to resolve param %s: %w", name, err)
+			}
+			params[name] = resolved
+		}
+	}
+	return params, notes, nil
+}
+
+// resolveBindingValue replaces the $(body.x), $(header.x) and $(extensions.x) expressions of a
+// TriggerBinding param value with the values of the event. Values that are not strings are
+// rendered as JSON.
+func resolveBindingValue(value string, data map[string]any) (string, error) {
+	var errs []error
+	resolved := bindingExpression.ReplaceAllStringFunc(value, func(expression string) string {
+		path := bindingExpression.FindStringSubmatch(expression)[1]
+		j := jsonpath.New("binding")
+		if err := j.Parse(fmt.Sprintf("{.%s}", path)); err != nil {
+			errs = append(errs, fmt.Errorf("invalid expression %s: %w", expression, err))
+			return expression
+		}
+		v, err := jsonPathValues(j, data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s not found in the event: %w", expression, err))
+			return expression
+		}
+		if s, ok := v.(string); ok {
+			return s
+		}
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	})
+	return resolved, errors.Join(errs...)
+}
+
+// renderTriggerTemplate renders the resources of the TriggerTemplate of a trigger with the params,
+// labelled like the EventListener labels them.
+func renderTriggerTemplate(ctx context.Context, el *unstructured.Unstructured, t namedTrigger, params map[string]string, eventID string) ([]map[string]any, error) {
+	spec, _, _ := unstructured.NestedMap(t.spec, "template", "spec")
+	if ref, _, _ := unstructured.NestedString(t.spec, "template", "ref"); ref != "" {
+		tt, err := getTriggersObject(ctx, "triggertemplate", el.GetNamespace(), ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TriggerTemplate %s/%s: %w", el.GetNamespace(), ref, err)
+		}
+		spec, _, _ = unstructured.NestedMap(tt.Object, "spec")
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("trigger %s has no template", t.name)
+	}
+
+	values := map[string]string{}
+	declared, _, _ := unstructured.NestedSlice(spec, "params")
+	var missing []string
+	for _, item := range declared {
+		param, _ := item.(map[string]any)
+		name, _ := param["name"].(string)
+		value, ok := params[name]
+		if !ok {
+			value, ok = param["default"].(string)
+		}
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		values[name] = value
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the TriggerTemplate params %s have no value and no default", strings.Join(missing, ", "))
+	}
+
+	uid := rand.String(5)
+	templates, _, _ := unstructured.NestedSlice(spec, "resourcetemplates")
+	resources := make([]map[string]any, 0, len(templates))
+	for _, template := range templates {
+		data, err := json.Marshal(template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
+		}
+		rendered := string(data)
+		for name, value := range values {
+			// The values are inserted in JSON strings
+			escaped, _ := json.Marshal(value)
+			quoted := string(escaped[1 : len(escaped)-1])
+			rendered = strings.ReplaceAll(rendered, fmt.Sprintf("$(tt.params.%s)", name), quoted)
+			rendered = strings.ReplaceAll(rendered, fmt.Sprintf("$(params.%s)", name), quoted)
+		}
+		rendered = strings.ReplaceAll(rendered, "$(uid)", uid)
+
+		resource := &unstructured.Unstructured{}
+		if err := json.Unmarshal([]byte(rendered), &resource.Object); err != nil {
+			return nil, fmt.Errorf("the rendered resource is not valid JSON: %w", err)
+		}
+		if resource.GetNamespace() == "" {
+			resource.SetNamespace(el.GetNamespace())
+		}
+		labels := resource.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[TriggersEventListenerLabelKey] = el.GetName()
+		labels[TriggersTriggerLabelKey] = t.name
+		labels[TriggersEventIDLabelKey] = eventID
+		resource.SetLabels(labels)
+		resources = append(resources, resource.Object)
+	}
+	return resources, nil
+}
+
+// runStartTools are the tools creating each kind of Tekton run, a trigger can only create runs the
+// server would start.
+var runStartTools = map[string]string{
+	"PipelineRun": "start_pipeline",
+	"TaskRun":     "start_task",
+	"CustomRun":   "start_customrun",
+}
+
+// createTriggerResources creates the resources rendered for a trigger, and returns their references.
+func createTriggerResources(ctx context.Context, resources []map[string]any) ([]string, error) {
+	// Nothing is created if one of the runs cannot be
+	for _, resource := range resources {
+		u := &unstructured.Unstructured{Object: resource}
+		gvk := u.GroupVersionKind()
+		if tool, ok := runStartTools[gvk.Kind]; ok && gvk.Group == "tekton.dev" && !ConfigFromContext(ctx).ToolEnabled(tool) {
+			return nil, fmt.Errorf("cannot create %s %s/%s%s, tool %s is disabled on this server", gvk.Kind, u.GetNamespace(), u.GetName(), u.GetGenerateName(), tool)
+		}
+	}
+
+	groupResources, err := restmapper.GetAPIGroupResources(kubeclient.Get(ctx).Discovery())
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the API resources: %w", err)
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+
+	var created []string
+	for _, resource := range resources {
+		u := &unstructured.Unstructured{Object: resource}
+		gvk := u.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return created, fmt.Errorf("failed to find the resource of %s: %w", gvk, err)
+		}
+		if err := ConfigFromContext(ctx).CheckNamespace(u.GetNamespace()); err != nil {
+			return created, err
+		}
+		// The template may name any kind, the caller must be allowed to create each of them
+		gvr := mapping.Resource
+		if err := authorize(ctx, "create", gvr.Group, gvr.Resource, "", u.GetNamespace(), ""); err != nil {
+			return created, err
+		}
+		obj, err := dynamicclient.Get(ctx).Resource(mapping.Resource).Namespace(u.GetNamespace()).Create(ctx, u, metav1.CreateOptions{})
+		if err != nil {
+			return created, fmt.Errorf("failed to create %s %s/%s%s: %w", gvk.Kind, u.GetNamespace(), u.GetName(), u.GetGenerateName(), err)
+		}
+		ref := fmt.Sprintf("%s %s/%s", gvk.Kind, obj.GetNamespace(), obj.GetName())
+		if k, ok := listKindOf(strings.ToLower(gvk.Kind)); ok && k.group == gvk.Group {
+			ref = TektonResourceURI(k.resourceType, obj.GetNamespace(), obj.GetName())
+		}
+		created = append(created, ref)
+	}
+	return created, nil
+}
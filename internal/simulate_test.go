@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	rtesting "knative.dev/pkg/reconciler/testing"
+)
+
+func TestResolveBindingValue(t *testing.T) {
+	data := map[string]any{
+		"body": map[string]any{
+			"ref":        "refs/heads/main",
+			"repository": map[string]any{"name": "app", "private": true, "stars": float64(3)},
+			"commits":    []any{map[string]any{"id": "abc"}, map[string]any{"id": "def"}},
+		},
+		"header":     map[string]any{"X-Github-Event": "push"},
+		"extensions": map[string]any{"short_sha": "abc1234"},
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "literal", value: "main", want: "main"},
+		{name: "body field", value: "$(body.ref)", want: "refs/heads/main"},
+		{name: "nested field", value: "$(body.repository.name)", want: "app"},
+		{name: "header", value: "$(header.X-Github-Event)", want: "push"},
+		{name: "extensions", value: "$(extensions.short_sha)", want: "abc1234"},
+		{name: "in a string", value: "$(body.repository.name)-$(extensions.short_sha)", want: "app-abc1234"},
+		{name: "array index", value: "$(body.commits[1].id)", want: "def"},
+		{name: "bracketed key", value: "$(body['ref'])", want: "refs/heads/main"},
+		{name: "object as JSON", value: "$(body.repository)", want: `{"name":"app","private":true,"stars":3}`},
+		{name: "bool as JSON", value: "$(body.repository.private)", want: "true"},
+		{name: "number as JSON", value: "$(body.repository.stars)", want: "3"},
+		{name: "array element as JSON", value: "$(body.commits[0])", want: `{"id":"abc"}`},
+		{name: "other expressions are left alone", value: "$(params.url) $(tt.params.url)", want: "$(params.url) $(tt.params.url)"},
+		{name: "missing field", value: "$(body.missing)", wantErr: true},
+		{name: "invalid expression", value: "$(body.commits[)", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := resolveBindingValue(test.value, data)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("resolveBindingValue() error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSimulateInterceptor(t *testing.T) {
+	env, err := newCELEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	celInterceptor := func(filter string, overlays ...map[string]any) map[string]any {
+		params := []any{map[string]any{"name": "filter", "value": filter}}
+		if len(overlays) > 0 {
+			var values []any
+			for _, o := range overlays {
+				values = append(values, o)
+			}
+			params = append(params, map[string]any{"name": "overlays", "value": values})
+		}
+		return map[string]any{"ref": map[string]any{"name": "cel"}, "params": params}
+	}
+
+	tests := []struct {
+		name           string
+		interceptor    map[string]any
+		want           simulatedInterceptor
+		passed         bool
+		wantErr        bool
+		wantExtensions map[string]any
+	}{{
+		name:        "filter passes",
+		interceptor: celInterceptor("header.match('X-GitHub-Event', 'push') && body.ref.startsWith('refs/heads/')"),
+		want:        simulatedInterceptor{Name: "cel", Result: "passed"},
+		passed:      true,
+	}, {
+		name:        "filter fails",
+		interceptor: celInterceptor("body.ref == 'refs/heads/release'"),
+		want:        simulatedInterceptor{Name: "cel", Result: "filtered out", Note: `filter "body.ref == 'refs/heads/release'" is false`},
+	}, {
+		name:        "filter is not a bool",
+		interceptor: celInterceptor("body.ref"),
+		want:        simulatedInterceptor{Name: "cel", Result: "error"},
+		wantErr:     true,
+	}, {
+		name:        "invalid filter",
+		interceptor: celInterceptor("body.ref =="),
+		want:        simulatedInterceptor{Name: "cel", Result: "error"},
+		wantErr:     true,
+	}, {
+		name: "overlays",
+		interceptor: celInterceptor("true",
+			map[string]any{"key": "short_sha", "expression": "body.sha.truncate(7)"},
+			map[string]any{"key": "repo.name", "expression": "body.repository.split('/')[1]"},
+		),
+		want:   simulatedInterceptor{Name: "cel", Result: "passed"},
+		passed: true,
+		wantExtensions: map[string]any{
+			"short_sha": "0123456",
+			"repo":      map[string]any{"name": "app"},
+		},
+	}, {
+		name: "legacy cel interceptor",
+		interceptor: map[string]any{"name": "only-main", "cel": map[string]any{
+			"filter":   "body.ref == 'refs/heads/main'",
+			"overlays": []any{map[string]any{"key": "branch", "expression": "body.ref.split('/')[2]"}},
+		}},
+		want:           simulatedInterceptor{Name: "only-main", Result: "passed"},
+		passed:         true,
+		wantExtensions: map[string]any{"branch": "main"},
+	}, {
+		name:        "other interceptors let the event through",
+		interceptor: map[string]any{"ref": map[string]any{"name": "github"}},
+		want: simulatedInterceptor{
+			Name:   "github",
+			Result: "not evaluated",
+			Note:   "only the cel interceptor is evaluated by the simulation, this one is assumed to let the event through",
+		},
+		passed: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event := celEvent{
+				body:       map[string]any{"ref": "refs/heads/main", "sha": "0123456789", "repository": "org/app"},
+				header:     http.Header{"X-Github-Event": {"push"}},
+				extensions: map[string]any{},
+			}
+			got, passed, err := simulateInterceptor(env, test.interceptor, event)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("simulateInterceptor() error = %v, want error %v", err, test.wantErr)
+			}
+			if got != test.want || passed != test.passed {
+				t.Errorf("got %+v, %v, want %+v, %v", got, passed, test.want, test.passed)
+			}
+			if test.wantExtensions == nil {
+				test.wantExtensions = map[string]any{}
+			}
+			if !reflect.DeepEqual(event.extensions, test.wantExtensions) {
+				t.Errorf("got extensions %v, want %v", event.extensions, test.wantExtensions)
+			}
+		})
+	}
+}
+
+func TestCreateTriggerResourcesDisabledTools(t *testing.T) {
+	run := func(apiVersion, kind string) map[string]any {
+		return map[string]any{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   map[string]any{"generateName": "build-", "namespace": "default"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		deny      []string
+		resources []map[string]any
+		wantErr   string
+	}{{
+		name:      "pipelinerun with start_pipeline disabled",
+		deny:      []string{"start_pipeline"},
+		resources: []map[string]any{run("tekton.dev/v1", "PipelineRun")},
+		wantErr:   "cannot create PipelineRun default/build-, tool start_pipeline is disabled on this server",
+	}, {
+		name:      "taskrun with start_task disabled",
+		deny:      []string{"start_task"},
+		resources: []map[string]any{run("v1", "ConfigMap"), run("tekton.dev/v1", "TaskRun")},
+		wantErr:   "cannot create TaskRun default/build-, tool start_task is disabled on this server",
+	}, {
+		name:      "customrun with start_customrun disabled",
+		deny:      []string{"start_customrun"},
+		resources: []map[string]any{run("tekton.dev/v1beta1", "CustomRun")},
+		wantErr:   "cannot create CustomRun default/build-, tool start_customrun is disabled on this server",
+	}, {
+		name:      "other tools disabled",
+		deny:      []string{"start_task"},
+		resources: []map[string]any{run("tekton.dev/v1", "PipelineRun")},
+	}, {
+		name:      "same kind in another group",
+		deny:      []string{"start_pipeline"},
+		resources: []map[string]any{run("example.com/v1", "PipelineRun")},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, _ := rtesting.SetupFakeContext(t)
+			ctx = WithConfig(ctx, &Config{Tools: Filter{Deny: test.deny}})
+			_, err := createTriggerResources(ctx, test.resources)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("got error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			// The fake discovery has no resources, the runs get past the tool check
+			if err == nil || !strings.HasPrefix(err.Error(), "failed to find the resource of") {
+				t.Errorf("got error %v, want the resource not to be found", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+// runOutcome summarizes the state of a PipelineRun, a TaskRun or a CustomRun.
+type runOutcome struct {
+	Kind           string         `json:"kind"`
+	Name           string         `json:"name"`
+	Namespace      string         `json:"namespace"`
+	Done           bool           `json:"done"`
+	Status         string         `json:"status,omitempty"`
+	Reason         string         `json:"reason,omitempty"`
+	Message        string         `json:"message,omitempty"`
+	StartTime      *metav1.Time   `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time   `json:"completionTime,omitempty"`
+	Duration       string         `json:"duration,omitempty"`
+	Results        any            `json:"results,omitempty"`
+	TaskRuns       []childOutcome `json:"taskRuns,omitempty"`
+	Steps          []stepOutcome  `json:"steps,omitempty"`
+}
+
+// childOutcome summarizes the state of a child TaskRun or CustomRun of a PipelineRun.
+type childOutcome struct {
+	Kind         string `json:"kind,omitempty"`
+	Name         string `json:"name"`
+	PipelineTask string `json:"pipelineTask,omitempty"`
+	Status       string `json:"status,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Duration     string `json:"duration,omitempty"`
+}
+
+// stepOutcome summarizes the state of a step of a TaskRun.
+type stepOutcome struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// succeededCondition returns the status, reason and message of the Succeeded condition.
+func succeededCondition(status duckv1.Status) (string, string, string) {
+	c := status.GetCondition(apis.ConditionSucceeded)
+	if c == nil {
+		return "", "", ""
+	}
+	return string(c.Status), c.Reason, c.Message
+}
+
+// runDuration returns the duration of a run, up to now if it hasn't completed yet.
+func runDuration(start, completion *metav1.Time) string {
+	if start == nil {
+		return ""
+	}
+	end := time.Now()
+	if completion != nil {
+		end = completion.Time
+	}
+	return end.Sub(start.Time).Round(time.Second).String()
+}
+
+func pipelineRunOutcome(ctx context.Context, pr *v1.PipelineRun) runOutcome {
+	status, reason, message := succeededCondition(pr.Status.Status)
+	outcome := runOutcome{
+		Kind:           "PipelineRun",
+		Name:           pr.Name,
+		Namespace:      pr.Namespace,
+		Done:           pr.IsDone(),
+		Status:         status,
+		Reason:         reason,
+		Message:        message,
+		StartTime:      pr.Status.StartTime,
+		CompletionTime: pr.Status.CompletionTime,
+		Duration:       runDuration(pr.Status.StartTime, pr.Status.CompletionTime),
+	}
+	if len(pr.Status.Results) > 0 {
+		outcome.Results = pr.Status.Results
+	}
+
+	taskRunLister := taskruninformer.Get(ctx).Lister().TaskRuns(pr.Namespace)
+	customRunLister := customruninformer.Get(ctx).Lister().CustomRuns(pr.Namespace)
+	for _, child := range pr.Status.ChildReferences {
+		c := childOutcome{
+			Kind:         child.Kind,
+			Name:         child.Name,
+			PipelineTask: child.PipelineTaskName,
+		}
+		switch child.Kind {
+		case "TaskRun":
+			if tr, err := taskRunLister.Get(child.Name); err == nil {
+				c.Status, c.Reason, _ = succeededCondition(tr.Status.Status)
+				c.Duration = runDuration(tr.Status.StartTime, tr.Status.CompletionTime)
+			}
+		case "CustomRun":
+			if cr, err := customRunLister.Get(child.Name); err == nil {
+				c.Status, c.Reason, _ = succeededCondition(cr.Status.Status)
+				c.Duration = runDuration(cr.Status.StartTime, cr.Status.CompletionTime)
+			}
+		}
+		outcome.TaskRuns = append(outcome.TaskRuns, c)
+	}
+
+	return outcome
+}
+
+func taskRunOutcome(tr *v1.TaskRun) runOutcome {
+	status, reason, message := succeededCondition(tr.Status.Status)
+	outcome := runOutcome{
+		Kind:           "TaskRun",
+		Name:           tr.Name,
+		Namespace:      tr.Namespace,
+		Done:           tr.IsDone(),
+		Status:         status,
+		Reason:         reason,
+		Message:        message,
+		StartTime:      tr.Status.StartTime,
+		CompletionTime: tr.Status.CompletionTime,
+		Duration:       runDuration(tr.Status.StartTime, tr.Status.CompletionTime),
+	}
+	if len(tr.Status.Results) > 0 {
+		outcome.Results = tr.Status.Results
+	}
+
+	for _, step := range tr.Status.Steps {
+		outcome.Steps = append(outcome.Steps, stepStateOutcome(step))
+	}
+
+	return outcome
+}
+
+func customRunOutcome(cr *v1beta1.CustomRun) runOutcome {
+	status, reason, message := succeededCondition(cr.Status.Status)
+	outcome := runOutcome{
+		Kind:           "CustomRun",
+		Name:           cr.Name,
+		Namespace:      cr.Namespace,
+		Done:           cr.IsDone(),
+		Status:         status,
+		Reason:         reason,
+		Message:        message,
+		StartTime:      cr.Status.StartTime,
+		CompletionTime: cr.Status.CompletionTime,
+		Duration:       runDuration(cr.Status.StartTime, cr.Status.CompletionTime),
+	}
+	if len(cr.Status.Results) > 0 {
+		outcome.Results = cr.Status.Results
+	}
+	return outcome
+}
+
+func stepStateOutcome(step v1.StepState) stepOutcome {
+	s := stepOutcome{Name: step.Name, State: "waiting"}
+	switch {
+	case step.Terminated != nil:
+		s.State = "terminated"
+		s.ExitCode = &step.Terminated.ExitCode
+		s.Reason = step.Terminated.Reason
+		if step.TerminationReason != "" {
+			s.Reason = step.TerminationReason
+		}
+	case step.Running != nil:
+		s.State = "running"
+	case step.Waiting != nil:
+		s.Reason = step.Waiting.Reason
+	}
+	return s
+}
@@ -0,0 +1,366 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+	methodResourcesUpdated     = "notifications/resources/updated"
+	methodResourcesListChanged = "notifications/resources/list_changed"
+)
+
+// listChangedInterval is the minimum interval between two resources/list_changed notifications,
+// so that bursts of created or deleted objects only send one notification.
+const listChangedInterval = time.Second
+
+// MessageInterceptor handles the messages the MCP server doesn't implement before they reach it. It
+// returns the message to pass on to the server, or the response to send instead.
+type MessageInterceptor func(ctx context.Context, sessionID string, message json.RawMessage) (json.RawMessage, mcp.JSONRPCMessage)
+
+// Subscriptions implements resources/subscribe: it tracks which sessions are subscribed to which
+// tekton:// URIs, and notifies them when the informers see the objects change. It also notifies every
+// session when objects are added or deleted.
+type Subscriptions struct {
+	server *server.MCPServer
+
+	mu       sync.Mutex
+	sessions map[string]server.ClientSession
+	// subscribers are the IDs of the sessions subscribed to each URI
+	subscribers map[string]map[string]bool
+	listChanged bool
+}
+
+// NewSubscriptions creates an empty set of subscriptions.
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{
+		sessions:    map[string]server.ClientSession{},
+		subscribers: map[string]map[string]bool{},
+	}
+}
+
+// RegisterSession is a hook tracking the sessions of the MCP server, to notify them.
+func (m *Subscriptions) RegisterSession(_ context.Context, session server.ClientSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.SessionID()] = session
+}
+
+// unregisterSession forgets a session and its subscriptions.
+func (m *Subscriptions) unregisterSession(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	for uri, subscribers := range m.subscribers {
+		delete(subscribers, id)
+		if len(subscribers) == 0 {
+			delete(m.subscribers, uri)
+		}
+	}
+}
+
+// Start watches the informers of the listed kinds and sends the notifications of the MCP server,
+// until the context is done.
+func (m *Subscriptions) Start(ctx context.Context, s *server.MCPServer) error {
+	m.server = s
+	for _, k := range listKinds {
+		resourceType := k.resourceType
+		_, err := k.informer(ctx).AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj any, isInInitialList bool) {
+				if !isInInitialList {
+					m.changed(ctx, resourceType, obj, true)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj any) {
+				o, ok1 := oldObj.(metav1.Object)
+				n, ok2 := newObj.(metav1.Object)
+				// Skip the periodic resyncs
+				if ok1 && ok2 && o.GetResourceVersion() != n.GetResourceVersion() {
+					m.changed(ctx, resourceType, newObj, false)
+				}
+			},
+			DeleteFunc: func(obj any) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				m.changed(ctx, resourceType, obj, true)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to watch %ss: %w", k.kind, err)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(listChangedInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.mu.Lock()
+				changed := m.listChanged
+				m.listChanged = false
+				m.mu.Unlock()
+				if changed {
+					m.notify(ctx, m.allSessions(), methodResourcesListChanged, nil)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// changed notifies the subscribers of an object that it changed, and schedules a list_changed
+// notification if the object was added or deleted.
+func (m *Subscriptions) changed(ctx context.Context, resourceType string, obj any, listChanged bool) {
+	o, ok := obj.(metav1.Object)
+	if !ok || !ConfigFromContext(ctx).Namespaces.Allowed(o.GetNamespace()) {
+		return
+	}
+	uri := TektonResourceURI(resourceType, o.GetNamespace(), o.GetName())
+
+	m.mu.Lock()
+	var sessions []server.ClientSession
+	for id := range m.subscribers[uri] {
+		if session, ok := m.sessions[id]; ok {
+			sessions = append(sessions, session)
+		}
+	}
+	if listChanged {
+		m.listChanged = true
+	}
+	m.mu.Unlock()
+
+	m.notify(ctx, sessions, methodResourcesUpdated, map[string]any{"uri": uri})
+}
+
+func (m *Subscriptions) allSessions() []server.ClientSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make([]server.ClientSession, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// notify sends a notification to the sessions. The sessions whose notification channel is full are
+// forgotten: their client went away, or can't keep up with the notifications.
+func (m *Subscriptions) notify(ctx context.Context, sessions []server.ClientSession, method string, params map[string]any) {
+	for _, session := range sessions {
+		if !session.Initialized() {
+			continue
+		}
+		if err := m.server.SendNotificationToClient(m.server.WithContext(ctx, session), method, params); err != nil {
+			slog.Warn(fmt.Sprintf("dropping session %s: %v", session.SessionID(), err))
+			m.unregisterSession(session.SessionID())
+		}
+	}
+}
+
+// Intercept is a MessageInterceptor handling resources/subscribe and resources/unsubscribe. Accepted
+// requests are passed on to the MCP server as a ping, for it to send the empty result.
+func (m *Subscriptions) Intercept(ctx context.Context, sessionID string, message json.RawMessage) (json.RawMessage, mcp.JSONRPCMessage) {
+	var request struct {
+		ID     mcp.RequestId `json:"id"`
+		Method string        `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil {
+		return message, nil
+	}
+	if request.Method != methodResourcesSubscribe && request.Method != methodResourcesUnsubscribe {
+		return message, nil
+	}
+	if request.ID == nil {
+		return message, nil
+	}
+
+	uri := request.Params.URI
+	if request.Method == methodResourcesSubscribe {
+		if err := checkSubscription(ctx, uri); err != nil {
+			return nil, mcp.NewJSONRPCError(request.ID, mcp.INVALID_PARAMS, err.Error(), nil)
+		}
+	}
+
+	m.mu.Lock()
+	if request.Method == methodResourcesSubscribe {
+		if m.subscribers[uri] == nil {
+			m.subscribers[uri] = map[string]bool{}
+		}
+		m.subscribers[uri][sessionID] = true
+	} else {
+		delete(m.subscribers[uri], sessionID)
+		if len(m.subscribers[uri]) == 0 {
+			delete(m.subscribers, uri)
+		}
+	}
+	m.mu.Unlock()
+
+	ping, _ := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      request.ID,
+		"method":  mcp.MethodPing,
+	})
+	return ping, nil
+}
+
+// checkSubscription returns an error if the URI is not a tekton:// URI of a listed kind that the
+// caller is allowed to get.
+func checkSubscription(ctx context.Context, uri string) error {
+	parts := strings.Split(strings.TrimPrefix(uri, "tekton://"), "/")
+	if !strings.HasPrefix(uri, "tekton://") || len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return fmt.Errorf("cannot subscribe to %s, only to tekton://<type>/<namespace>/<name> URIs", uri)
+	}
+	k, ok := listKindOf(parts[0])
+	if !ok {
+		return fmt.Errorf("cannot subscribe to %s, unknown resource type %s", uri, parts[0])
+	}
+	if err := ConfigFromContext(ctx).CheckNamespace(parts[1]); err != nil {
+		return err
+	}
+	return authorize(ctx, "get", k.group, k.resource(), "", parts[1], parts[2])
+}
+
+// InterceptStdio returns the stdin of the stdio transport with the messages handled by the
+// interceptor removed, their responses being written to stdout.
+func InterceptStdio(ctx context.Context, in io.Reader, out io.Writer, interceptor MessageInterceptor) (io.Reader, io.Writer) {
+	// The stdio server and the interceptor write to the same output
+	lockedOut := &lockedWriter{w: out}
+	r, w := io.Pipe()
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				message, response := interceptor(ctx, "stdio", json.RawMessage(line))
+				if response != nil {
+					data, _ := json.Marshal(response)
+					_, _ = fmt.Fprintf(lockedOut, "%s\n", data)
+				} else if _, werr := w.Write(append(bytes.TrimRight(message, "\n"), '\n')); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				_ = w.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return r, lockedOut
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// InterceptSSE wraps the handler of the SSE transport so that the messages posted by the clients go
+// through the interceptor. The responses of the interceptor are only sent in the body of the POST
+// response, like the SSE transport does for invalid messages.
+//
+// Each session is bound to the user who opened its stream: the messages posted for a session by
+// another user are rejected, as their responses would be sent on the stream of the owner.
+func InterceptSSE(next http.Handler, contextFunc HTTPContextFunc, interceptor MessageInterceptor) http.Handler {
+	// owners are the users who opened the streams of the sessions, by session ID
+	var owners sync.Map
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			stream := &sseStreamWriter{ResponseWriter: w, onSession: func(id string) {
+				owners.Store(id, UserFromContext(r.Context()))
+			}}
+			next.ServeHTTP(stream, r)
+			if stream.sessionID != "" {
+				owners.Delete(stream.sessionID)
+			}
+			return
+		}
+		sessionID := r.URL.Query().Get("sessionId")
+		if r.Method != http.MethodPost || sessionID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		owner, ok := owners.Load(sessionID)
+		if !ok || !sameUser(owner.(*User), UserFromContext(r.Context())) {
+			if ok {
+				slog.Warn(fmt.Sprintf("rejecting a message for session %s from another user", sessionID))
+			}
+			writeHTTPError(w, http.StatusNotFound, mcp.INVALID_REQUEST, fmt.Sprintf("unknown session %s", sessionID))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageBytes))
+		if err != nil {
+			writeHTTPError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "Failed to read the request body")
+			return
+		}
+		ctx := r.Context()
+		if contextFunc != nil {
+			ctx = contextFunc(ctx, r)
+		}
+		message, response := interceptor(ctx, sessionID, body)
+		if response != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(response)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(message))
+		r.ContentLength = int64(len(message))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sseStreamWriter reads the ID of the session from the endpoint event starting an SSE stream, and
+// calls onSession with it before the event reaches the client.
+type sseStreamWriter struct {
+	http.ResponseWriter
+	onSession func(id string)
+	sessionID string
+}
+
+func (s *sseStreamWriter) Write(p []byte) (int, error) {
+	if s.sessionID == "" {
+		if data, ok := bytes.CutPrefix(p, []byte("event: endpoint\ndata: ")); ok {
+			endpoint, _, _ := strings.Cut(string(data), "\r\n")
+			if u, err := url.Parse(strings.TrimSpace(endpoint)); err == nil && u.Query().Get("sessionId") != "" {
+				s.sessionID = u.Query().Get("sessionId")
+				s.onSession(s.sessionID)
+			}
+		}
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *sseStreamWriter) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -2,23 +2,19 @@ package internal
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"log/slog"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/pod"
 	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
-	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
 	pipelineinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipeline"
-	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
 	taskinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/task"
-	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
-	stepactioninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/stepaction"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 )
 
 func toolStartPipeline() mcp.Tool {
@@ -28,10 +24,35 @@ func toolStartPipeline() mcp.Tool {
 			mcp.Description("Name or Reference of the Pipeline to sart"),
 		),
 		mcp.WithString("namespace",
-			mcp.Description("Namespace where the Pipeline is located"),
-			mcp.DefaultString("default"),
+			mcp.Description("Namespace where the Pipeline is located, defaults to the server's default namespace"),
+		),
+		mcp.WithObject("params",
+			mcp.Description("Parameters of the Pipeline, as an object mapping each param name to a string, an array of strings or an object with string values"),
+		),
+		mcp.WithArray("workspaces",
+			mcp.Description("Workspace bindings, using the Tekton WorkspaceBinding format (name plus one of persistentVolumeClaim, volumeClaimTemplate, emptyDir, configMap or secret)"),
+			mcp.Items(workspaceBindingSchema),
+		),
+		mcp.WithString("service-account",
+			mcp.Description("ServiceAccount used to run the Pipeline's TaskRuns"),
+		),
+		mcp.WithObject("timeouts",
+			mcp.Description("Timeouts of the PipelineRun, as durations (e.g. 1h30m)"),
+			mcp.Properties(map[string]any{
+				"pipeline": map[string]any{"type": "string", "description": "Timeout of the whole PipelineRun"},
+				"tasks":    map[string]any{"type": "string", "description": "Timeout of the Pipeline's tasks"},
+				"finally":  map[string]any{"type": "string", "description": "Timeout of the Pipeline's finally tasks"},
+			}),
+		),
+		mcp.WithObject("pod-template",
+			mcp.Description("Pod template applied to the Pipeline's TaskRuns, using the Tekton PodTemplate format"),
+		),
+		mcp.WithBoolean("wait",
+			mcp.Description("Wait for the PipelineRun to finish and return its final status and results"),
+		),
+		mcp.WithString("wait-timeout",
+			mcp.Description("How long to wait for the PipelineRun to finish, as a duration (e.g. 30m), defaults to 10m"),
 		),
-		// TODO add "parameters" objects
 	)
 }
 
@@ -42,10 +63,38 @@ func toolStartTask() mcp.Tool {
 			mcp.Description("Name or Reference of the Task to sart"),
 		),
 		mcp.WithString("namespace",
-			mcp.Description("Namespace where the Task is located"),
-			mcp.DefaultString("default"),
+			mcp.Description("Namespace where the Task is located, defaults to the server's default namespace"),
+		),
+		mcp.WithObject("params",
+			mcp.Description("Parameters of the Task, as an object mapping each param name to a string, an array of strings or an object with string values"),
+		),
+		mcp.WithArray("workspaces",
+			mcp.Description("Workspace bindings, using the Tekton WorkspaceBinding format (name plus one of persistentVolumeClaim, volumeClaimTemplate, emptyDir, configMap or secret)"),
+			mcp.Items(workspaceBindingSchema),
+		),
+		mcp.WithString("service-account",
+			mcp.Description("ServiceAccount used to run the Task"),
+		),
+		mcp.WithString("timeout",
+			mcp.Description("Timeout of the TaskRun, as a duration (e.g. 1h30m)"),
+		),
+		mcp.WithObject("compute-resources",
+			mcp.Description("Compute resources (requests and limits) of the whole Task, using the Kubernetes ResourceRequirements format"),
+		),
+		mcp.WithArray("step-specs",
+			mcp.Description("Compute resources overrides for the Task's steps"),
+			mcp.Items(resourceOverrideSchema),
+		),
+		mcp.WithArray("sidecar-specs",
+			mcp.Description("Compute resources overrides for the Task's sidecars"),
+			mcp.Items(resourceOverrideSchema),
+		),
+		mcp.WithBoolean("wait",
+			mcp.Description("Wait for the TaskRun to finish and return its final status and results"),
+		),
+		mcp.WithString("wait-timeout",
+			mcp.Description("How long to wait for the TaskRun to finish, as a duration (e.g. 30m), defaults to 10m"),
 		),
-		// TODO add "parameters" objects
 	)
 }
 
@@ -54,15 +103,46 @@ func handlerStartPipeline(ctx context.Context, request mcp.CallToolRequest) (*mc
 	if !ok {
 		return nil, errors.New("name must be a string")
 	}
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	pipelineInformer := pipelineinformer.Get(ctx)
 	pipelineclientset := pipelineclient.Get(ctx)
 
-	if _, err := pipelineInformer.Lister().Pipelines(namespace).Get(name); err != nil {
+	pipeline, err := pipelineInformer.Lister().Pipelines(namespace).Get(name)
+	if err != nil {
 		return mcpError(fmt.Sprintf("Failed to get Pipeline %s/%s: %v", namespace, name, err)), nil
 	}
 
+	params, errs := parseRunParams(request)
+	var workspaces []v1.WorkspaceBinding
+	if _, err := DecodeParam(request, "workspaces", &workspaces); err != nil {
+		errs = append(errs, err.Error())
+	}
+	serviceAccount, err := OptionalParam[string](request, "service-account")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	var timeouts *v1.TimeoutFields
+	if _, err := DecodeParam(request, "timeouts", &timeouts); err != nil {
+		errs = append(errs, err.Error())
+	}
+	var podTemplate *pod.PodTemplate
+	if _, err := DecodeParam(request, "pod-template", &podTemplate); err != nil {
+		errs = append(errs, err.Error())
+	}
+	errs = append(errs, validateRunParams(pipeline.Spec.Params, params)...)
+	errs = append(errs, validateRunWorkspaces(pipelineWorkspaceDeclarations(pipeline.Spec.Workspaces), workspaces)...)
+	wait, waitTimeout, err := waitParams(request)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return invalidRunError("Pipeline", namespace, name, errs), nil
+	}
+
 	pr := &v1.PipelineRun{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "tekton.dev/v1",
@@ -76,18 +156,31 @@ func handlerStartPipeline(ctx context.Context, request mcp.CallToolRequest) (*mc
 			PipelineRef: &v1.PipelineRef{
 				Name: name,
 			},
+			Params:     params,
+			Workspaces: workspaces,
+			Timeouts:   timeouts,
+			TaskRunTemplate: v1.PipelineTaskRunTemplate{
+				ServiceAccountName: serviceAccount,
+				PodTemplate:        podTemplate,
+			},
 		},
 	}
 
-	if _, err := pipelineclientset.TektonV1().PipelineRuns(namespace).Create(ctx, pr, metav1.CreateOptions{}); err != nil {
+	created, err := pipelineclientset.TektonV1().PipelineRuns(namespace).Create(ctx, pr, metav1.CreateOptions{})
+	if err != nil {
 		return mcpError(fmt.Sprintf("Failed to create PipelineRun %s/%s: %v", namespace, name, err)), nil
 	}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.NewTextContent(fmt.Sprintf("Starting pipeline %s in namespace %s", name, namespace)),
-		},
-	}, nil
+	if !wait {
+		return runResult("pipelinerun", created, fmt.Sprintf("Started pipeline %s", name), nil)
+	}
+
+	run, done, err := waitForPipelineRun(ctx, request, namespace, created.Name, waitTimeout)
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to wait for PipelineRun %s/%s: %v", namespace, created.Name, err)), nil
+	}
+	outcome := pipelineRunOutcome(ctx, run)
+	return runResult("pipelinerun", run, waitedRunSummary("PipelineRun", outcome, done), outcome)
 }
 
 func handlerStartTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -95,15 +188,55 @@ func handlerStartTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.Ca
 	if !ok {
 		return nil, errors.New("name must be a string")
 	}
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	taskInformer := taskinformer.Get(ctx)
 	pipelineclientset := pipelineclient.Get(ctx)
 
-	if _, err := taskInformer.Lister().Tasks(namespace).Get(name); err != nil {
+	task, err := taskInformer.Lister().Tasks(namespace).Get(name)
+	if err != nil {
 		return mcpError(fmt.Sprintf("Failed to get Task %s/%s: %v", namespace, name, err)), nil
 	}
 
+	params, errs := parseRunParams(request)
+	var workspaces []v1.WorkspaceBinding
+	if _, err := DecodeParam(request, "workspaces", &workspaces); err != nil {
+		errs = append(errs, err.Error())
+	}
+	serviceAccount, err := OptionalParam[string](request, "service-account")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	var timeout *metav1.Duration
+	if _, err := DecodeParam(request, "timeout", &timeout); err != nil {
+		errs = append(errs, err.Error())
+	}
+	var computeResources *corev1.ResourceRequirements
+	if _, err := DecodeParam(request, "compute-resources", &computeResources); err != nil {
+		errs = append(errs, err.Error())
+	}
+	var stepSpecs []v1.TaskRunStepSpec
+	if _, err := DecodeParam(request, "step-specs", &stepSpecs); err != nil {
+		errs = append(errs, err.Error())
+	}
+	var sidecarSpecs []v1.TaskRunSidecarSpec
+	if _, err := DecodeParam(request, "sidecar-specs", &sidecarSpecs); err != nil {
+		errs = append(errs, err.Error())
+	}
+	errs = append(errs, validateRunParams(task.Spec.Params, params)...)
+	errs = append(errs, validateRunWorkspaces(taskWorkspaceDeclarations(task.Spec.Workspaces), workspaces)...)
+	errs = append(errs, validateStepSpecs(task.Spec, stepSpecs, sidecarSpecs)...)
+	wait, waitTimeout, err := waitParams(request)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return invalidRunError("Task", namespace, name, errs), nil
+	}
+
 	pr := &v1.TaskRun{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "tekton.dev/v1",
@@ -117,346 +250,53 @@ func handlerStartTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.Ca
 			TaskRef: &v1.TaskRef{
 				Name: name,
 			},
+			Params:             params,
+			Workspaces:         workspaces,
+			ServiceAccountName: serviceAccount,
+			Timeout:            timeout,
+			ComputeResources:   computeResources,
+			StepSpecs:          stepSpecs,
+			SidecarSpecs:       sidecarSpecs,
 		},
 	}
 
-	if _, err := pipelineclientset.TektonV1().TaskRuns(namespace).Create(ctx, pr, metav1.CreateOptions{}); err != nil {
-		return mcpError(fmt.Sprintf("Failed to create TaskRun %s/%s: %v", namespace, name, err)), nil
-	}
-
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.NewTextContent(fmt.Sprintf("Starting task %s in namespace %s", name, namespace)),
-		},
-	}, nil
-}
-
-func handlerListTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	taskInformer := taskinformer.Get(ctx)
-	namespace, err := OptionalParam[string](request, "namespace")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	lselector, err := OptionalParam[string](request, "label-selector")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	prefix, err := OptionalParam[string](request, "prefix")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-
-	var selector labels.Selector
-	if lselector != "" {
-		selector, err = labels.Parse(lselector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		selector = labels.NewSelector()
-	}
-
-	var trs []*v1.Task
-
-	if namespace == "" {
-		// No namespace, searching all PipelineRuns
-		trs, err = taskInformer.Lister().List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		trs, err = taskInformer.Lister().Tasks(namespace).List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	}
-
-	// Filter after the fact
-	if prefix != "" {
-		filteredTRs := []*v1.Task{}
-		for _, pr := range trs {
-			if strings.HasPrefix(pr.Name, prefix) {
-				filteredTRs = append(filteredTRs, pr)
-			}
-		}
-		trs = filteredTRs
-	}
-
-	jsonData, err := json.Marshal(trs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
-	}
-
-	return mcp.NewToolResultText(string(jsonData)), nil
-}
-
-func handlerListTaskRun(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	taskRunInformer := taskruninformer.Get(ctx)
-	namespace, err := OptionalParam[string](request, "namespace")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	lselector, err := OptionalParam[string](request, "label-selector")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	prefix, err := OptionalParam[string](request, "prefix")
+	created, err := pipelineclientset.TektonV1().TaskRuns(namespace).Create(ctx, pr, metav1.CreateOptions{})
 	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-
-	var selector labels.Selector
-	if lselector != "" {
-		selector, err = labels.Parse(lselector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		selector = labels.NewSelector()
-	}
-
-	var trs []*v1.TaskRun
-
-	if namespace == "" {
-		// No namespace, searching all PipelineRuns
-		trs, err = taskRunInformer.Lister().List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		trs, err = taskRunInformer.Lister().TaskRuns(namespace).List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	}
-
-	// Filter after the fact
-	if prefix != "" {
-		filteredTRs := []*v1.TaskRun{}
-		for _, pr := range trs {
-			if strings.HasPrefix(pr.Name, prefix) {
-				filteredTRs = append(filteredTRs, pr)
-			}
-		}
-		trs = filteredTRs
-	}
-
-	jsonData, err := json.Marshal(trs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
-	}
-
-	return mcp.NewToolResultText(string(jsonData)), nil
-}
-
-func handlerListStepaction(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	stepactionInformer := stepactioninformer.Get(ctx)
-	namespace, err := OptionalParam[string](request, "namespace")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	lselector, err := OptionalParam[string](request, "label-selector")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	prefix, err := OptionalParam[string](request, "prefix")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-
-	var selector labels.Selector
-	if lselector != "" {
-		selector, err = labels.Parse(lselector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		selector = labels.NewSelector()
-	}
-
-	var trs []*v1beta1.StepAction
-
-	if namespace == "" {
-		// No namespace, searching all PipelineRuns
-		trs, err = stepactionInformer.Lister().List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		trs, err = stepactionInformer.Lister().StepActions(namespace).List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	}
-
-	// Filter after the fact
-	if prefix != "" {
-		filteredTRs := []*v1beta1.StepAction{}
-		for _, pr := range trs {
-			if strings.HasPrefix(pr.Name, prefix) {
-				filteredTRs = append(filteredTRs, pr)
-			}
-		}
-		trs = filteredTRs
-	}
-
-	jsonData, err := json.Marshal(trs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
-	}
-
-	return mcp.NewToolResultText(string(jsonData)), nil
-}
-
-func handlerListPipeline(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	pipelineInformer := pipelineinformer.Get(ctx)
-	namespace, err := OptionalParam[string](request, "namespace")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	lselector, err := OptionalParam[string](request, "label-selector")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	prefix, err := OptionalParam[string](request, "prefix")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-
-	var selector labels.Selector
-	if lselector != "" {
-		selector, err = labels.Parse(lselector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		selector = labels.NewSelector()
-	}
-
-	var prs []*v1.Pipeline
-
-	if namespace == "" {
-		// No namespace, searching all Pipelines
-		prs, err = pipelineInformer.Lister().List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		prs, err = pipelineInformer.Lister().Pipelines(namespace).List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
+		return mcpError(fmt.Sprintf("Failed to create TaskRun %s/%s: %v", namespace, name, err)), nil
 	}
 
-	// Filter after the fact
-	if prefix != "" {
-		filteredPRs := []*v1.Pipeline{}
-		for _, pr := range prs {
-			if strings.HasPrefix(pr.Name, prefix) {
-				filteredPRs = append(filteredPRs, pr)
-			}
-		}
-		prs = filteredPRs
+	if !wait {
+		return runResult("taskrun", created, fmt.Sprintf("Started task %s", name), nil)
 	}
 
-	jsonData, err := json.Marshal(prs)
+	run, done, err := waitForTaskRun(ctx, request, namespace, created.Name, waitTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
+		return mcpError(fmt.Sprintf("Failed to wait for TaskRun %s/%s: %v", namespace, created.Name, err)), nil
 	}
-
-	return mcp.NewToolResultText(string(jsonData)), nil
+	outcome := taskRunOutcome(run)
+	return runResult("taskrun", run, waitedRunSummary("TaskRun", outcome, done), outcome)
 }
 
-func handlerListPipelineRun(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	pipelineRunInformer := pipelineruninformer.Get(ctx)
-	namespace, err := OptionalParam[string](request, "namespace")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	lselector, err := OptionalParam[string](request, "label-selector")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-	prefix, err := OptionalParam[string](request, "prefix")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
-
-	var selector labels.Selector
-	if lselector != "" {
-		selector, err = labels.Parse(lselector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		selector = labels.NewSelector()
-	}
-
-	var prs []*v1.PipelineRun
-
-	if namespace == "" {
-		// No namespace, searching all PipelineRuns
-		prs, err = pipelineRunInformer.Lister().List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	} else {
-		prs, err = pipelineRunInformer.Lister().PipelineRuns(namespace).List(selector)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-	}
-
-	// Filter after the fact
-	if prefix != "" {
-		filteredPRs := []*v1.PipelineRun{}
-		for _, pr := range prs {
-			if strings.HasPrefix(pr.Name, prefix) {
-				filteredPRs = append(filteredPRs, pr)
-			}
+func AddTools(s *server.MCPServer, cfg *Config) {
+	addTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		if !cfg.ToolEnabled(tool.Name) {
+			slog.Info(fmt.Sprintf("Tool %s is disabled", tool.Name))
+			return
 		}
-		prs = filteredPRs
+		s.AddTool(tool, handler)
+	}
+
+	addTool(toolStartPipeline(), handlerStartPipeline)
+	addTool(toolStartTask(), handlerStartTask)
+	addTool(toolStartCustomRun(), handlerStartCustomRun)
+	addTool(toolCancelPipelineRun(), handlerCancelPipelineRun)
+	addTool(toolCancelTaskRun(), handlerCancelTaskRun)
+	addTool(toolRerun(), handlerRerun)
+	addTool(toolGetLogs(), handlerGetLogs)
+	addTool(toolGetRunTrigger(), handlerGetRunTrigger)
+	addTool(toolSimulateTrigger(), handlerSimulateTrigger)
+	addTool(toolInspectProvenance(), handlerInspectProvenance)
+	for _, k := range listKinds {
+		addTool(k.listTool(), k.handlerList())
 	}
-
-	jsonData, err := json.Marshal(prs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
-	}
-
-	return mcp.NewToolResultText(string(jsonData)), nil
-}
-
-func AddTools(s *server.MCPServer) {
-	s.AddTool(toolStartPipeline(), handlerStartPipeline)
-	s.AddTool(toolStartTask(), handlerStartTask)
-	s.AddTool(mcp.NewTool("list_pipelineruns",
-		mcp.WithDescription("List pipelineruns in the cluster with filtering options"),
-		mcp.WithString("namespace", mcp.Description("Which namespace to use to look for PipelineRuns")),
-		mcp.WithString("prefix", mcp.Description("Name prefix to filter PipelineRuns")),
-		mcp.WithString("label-selector", mcp.Description("Label selector to filter PipelineRuns")),
-	), handlerListPipelineRun)
-	s.AddTool(mcp.NewTool("list_taskruns",
-		mcp.WithDescription("List taskruns in the cluster with filtering options"),
-		mcp.WithString("namespace", mcp.Description("Which namespace to use to look for Taskruns")),
-		mcp.WithString("prefix", mcp.Description("Name prefix to filter Taskruns")),
-		mcp.WithString("label-selector", mcp.Description("Label selector to filter Taskruns")),
-	), handlerListTaskRun)
-	s.AddTool(mcp.NewTool("list_pipelines",
-		mcp.WithDescription("List pipelines in the cluster with filtering options"),
-		mcp.WithString("namespace", mcp.Description("Which namespace to use to look for Pipeline")),
-		mcp.WithString("prefix", mcp.Description("Name prefix to filter Pipeline")),
-		mcp.WithString("label-selector", mcp.Description("Label selector to filter Pipeline")),
-	), handlerListPipeline)
-	s.AddTool(mcp.NewTool("list_tasks",
-		mcp.WithDescription("List tasks in the cluster with filtering options"),
-		mcp.WithString("namespace", mcp.Description("Which namespace to use to look for Task")),
-		mcp.WithString("prefix", mcp.Description("Name prefix to filter Task")),
-		mcp.WithString("label-selector", mcp.Description("Label selector to filter Task")),
-	), handlerListTask)
-	s.AddTool(mcp.NewTool("list_stepactions",
-		mcp.WithDescription("List stepactions in the cluster with filtering options"),
-		mcp.WithString("namespace", mcp.Description("Which namespace to use to look for Stepactions")),
-		mcp.WithString("prefix", mcp.Description("Name prefix to filter Stepactions")),
-		mcp.WithString("label-selector", mcp.Description("Label selector to filter Stepactions")),
-	), handlerListStepaction)
 }
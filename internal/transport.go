@@ -0,0 +1,405 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// SessionIDHeader is the HTTP header carrying the session of the streamable HTTP transport
+const SessionIDHeader = "Mcp-Session-Id"
+
+// maxMessageBytes is the maximum size of a message posted to the streamable HTTP transport.
+const maxMessageBytes = 4 * 1024 * 1024
+
+// defaultSessionIdleTimeout is how long a session of the streamable HTTP transport is kept without
+// requests before it expires.
+const defaultSessionIdleTimeout = 30 * time.Minute
+
+// HTTPContextFunc customizes the context used to handle a message received over HTTP.
+type HTTPContextFunc func(ctx context.Context, r *http.Request) context.Context
+
+// injectionContext is a request context that falls back to another context for values, so that
+// handlers called over HTTP can access the informers and clients injected in the server context.
+type injectionContext struct {
+	context.Context
+	injection context.Context
+}
+
+func (c injectionContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.injection.Value(key)
+}
+
+// WithInjection returns an HTTPContextFunc that makes the values of the injection context
+// (informers, clients) available to the handlers, while keeping the request cancellation.
+func WithInjection(injection context.Context) HTTPContextFunc {
+	return func(ctx context.Context, _ *http.Request) context.Context {
+		return injectionContext{Context: ctx, injection: injection}
+	}
+}
+
+// httpSession is a session of the streamable HTTP transport. Its notification channel carries the
+// notifications that are not related to a request, sent on the GET stream.
+type httpSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+	streaming     atomic.Bool
+	// user is the authenticated caller who created the session, nil without authentication.
+	user *User
+	// lastSeen is the time of the last request of the session, in Unix nanoseconds.
+	lastSeen atomic.Int64
+}
+
+func (s *httpSession) SessionID() string { return s.id }
+
+func (s *httpSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+
+func (s *httpSession) Initialize() { s.initialized.Store(true) }
+
+func (s *httpSession) Initialized() bool { return s.initialized.Load() }
+
+// expired returns true if the session had no request for the idle timeout. Sessions with an open
+// GET stream don't expire.
+func (s *httpSession) expired(idleTimeout time.Duration) bool {
+	return !s.streaming.Load() && time.Since(time.Unix(0, s.lastSeen.Load())) > idleTimeout
+}
+
+// sameUser returns true if both callers are the same user, or if neither is authenticated.
+func sameUser(a, b *User) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Name == b.Name && a.UID == b.UID
+}
+
+// requestSession is the session seen by the handlers of a single POST request, so that the
+// notifications they send (e.g. progress) are streamed back in the response to that request.
+type requestSession struct {
+	*httpSession
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *requestSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+var (
+	_ server.ClientSession = (*httpSession)(nil)
+	_ server.ClientSession = (*requestSession)(nil)
+)
+
+// StreamableHTTPServer serves an MCP server over the streamable HTTP transport: clients POST
+// JSON-RPC messages and get the responses either as JSON or as an event stream, and can GET an
+// event stream to receive the notifications of their session.
+type StreamableHTTPServer struct {
+	server      *server.MCPServer
+	path        string
+	contextFunc HTTPContextFunc
+	interceptor MessageInterceptor
+	idleTimeout time.Duration
+	sessions    sync.Map
+}
+
+// NewStreamableHTTPServer creates a streamable HTTP transport for the MCP server, served on the given path.
+func NewStreamableHTTPServer(s *server.MCPServer, path string, contextFunc HTTPContextFunc) *StreamableHTTPServer {
+	if path == "" {
+		path = "/mcp"
+	}
+	return &StreamableHTTPServer{
+		server:      s,
+		path:        path,
+		contextFunc: contextFunc,
+		idleTimeout: defaultSessionIdleTimeout,
+	}
+}
+
+// SetInterceptor sets the interceptor handling the messages before the MCP server.
+func (s *StreamableHTTPServer) SetInterceptor(interceptor MessageInterceptor) {
+	s.interceptor = interceptor
+}
+
+// SetSessionIdleTimeout sets how long a session is kept without requests, 30 minutes by default.
+func (s *StreamableHTTPServer) SetSessionIdleTimeout(idleTimeout time.Duration) {
+	s.idleTimeout = idleTimeout
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *StreamableHTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.path {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.handlePost(w, r)
+	case http.MethodGet:
+		s.handleGet(w, r)
+	case http.MethodDelete:
+		s.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *StreamableHTTPServer) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageBytes))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "Failed to read the request body")
+		return
+	}
+
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	var messages []json.RawMessage
+	if batch {
+		err = json.Unmarshal(body, &messages)
+	} else {
+		messages = []json.RawMessage{body}
+	}
+	if err != nil || len(messages) == 0 {
+		writeHTTPError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "Parse error")
+		return
+	}
+
+	hasRequests, initialize := false, false
+	for _, message := range messages {
+		var base struct {
+			Method mcp.MCPMethod `json:"method"`
+			ID     any           `json:"id"`
+		}
+		if err := json.Unmarshal(message, &base); err != nil {
+			writeHTTPError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "Parse error")
+			return
+		}
+		if base.ID != nil && base.Method != "" {
+			hasRequests = true
+		}
+		if base.Method == mcp.MethodInitialize {
+			initialize = true
+		}
+	}
+
+	var session *httpSession
+	if initialize {
+		s.expireSessions()
+		session = &httpSession{
+			id:            uuid.New().String(),
+			notifications: make(chan mcp.JSONRPCNotification, 100),
+			user:          UserFromContext(r.Context()),
+		}
+		session.lastSeen.Store(time.Now().UnixNano())
+		if err := s.server.RegisterSession(r.Context(), session); err != nil {
+			writeHTTPError(w, http.StatusInternalServerError, mcp.INTERNAL_ERROR, fmt.Sprintf("Session registration failed: %v", err))
+			return
+		}
+		s.sessions.Store(session.id, session)
+	} else {
+		session, err = s.session(r)
+		if err != nil {
+			writeHTTPError(w, http.StatusNotFound, mcp.INVALID_REQUEST, err.Error())
+			return
+		}
+	}
+	w.Header().Set(SessionIDHeader, session.id)
+
+	reqSession := &requestSession{
+		httpSession:   session,
+		notifications: make(chan mcp.JSONRPCNotification, 100),
+	}
+	ctx := s.server.WithContext(r.Context(), reqSession)
+	if s.contextFunc != nil {
+		ctx = s.contextFunc(ctx, r)
+	}
+
+	handleMessage := func(message json.RawMessage) mcp.JSONRPCMessage {
+		if s.interceptor != nil {
+			var response mcp.JSONRPCMessage
+			if message, response = s.interceptor(ctx, session.id, message); response != nil {
+				return response
+			}
+		}
+		return s.server.HandleMessage(ctx, message)
+	}
+
+	if !hasRequests {
+		for _, message := range messages {
+			handleMessage(message)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	handle := func() []mcp.JSONRPCMessage {
+		var responses []mcp.JSONRPCMessage
+		for _, message := range messages {
+			if response := handleMessage(message); response != nil {
+				responses = append(responses, response)
+			}
+		}
+		return responses
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok || !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		responses := handle()
+		w.Header().Set("Content-Type", "application/json")
+		if batch {
+			_ = json.NewEncoder(w).Encode(responses)
+		} else if len(responses) > 0 {
+			_ = json.NewEncoder(w).Encode(responses[0])
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	done := make(chan []mcp.JSONRPCMessage, 1)
+	go func() { done <- handle() }()
+
+	for {
+		select {
+		case notification := <-reqSession.notifications:
+			writeEvent(w, flusher, notification)
+		case responses := <-done:
+			// Send the notifications sent right before the handlers returned, then the responses
+			for len(reqSession.notifications) > 0 {
+				writeEvent(w, flusher, <-reqSession.notifications)
+			}
+			for _, response := range responses {
+				writeEvent(w, flusher, response)
+			}
+			return
+		}
+	}
+}
+
+func (s *StreamableHTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	session, err := s.session(r)
+	if err != nil {
+		writeHTTPError(w, http.StatusNotFound, mcp.INVALID_REQUEST, err.Error())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok || !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "Not acceptable, the GET stream requires text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	if !session.streaming.CompareAndSwap(false, true) {
+		http.Error(w, "A stream is already open for this session", http.StatusConflict)
+		return
+	}
+	defer func() {
+		session.lastSeen.Store(time.Now().UnixNano())
+		session.streaming.Store(false)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(SessionIDHeader, session.id)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case notification := <-session.notifications:
+			writeEvent(w, flusher, notification)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *StreamableHTTPServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	session, err := s.session(r)
+	if err != nil {
+		writeHTTPError(w, http.StatusNotFound, mcp.INVALID_REQUEST, err.Error())
+		return
+	}
+	s.removeSession(session.id)
+	w.WriteHeader(http.StatusOK)
+}
+
+// session returns the session identified by the request's session header. Sessions are only
+// available to the user who created them, the sessions of other users are reported as unknown.
+func (s *StreamableHTTPServer) session(r *http.Request) (*httpSession, error) {
+	id := r.Header.Get(SessionIDHeader)
+	if id == "" {
+		return nil, fmt.Errorf("missing %s header", SessionIDHeader)
+	}
+	value, ok := s.sessions.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown session %s", id)
+	}
+	session := value.(*httpSession)
+	if !sameUser(session.user, UserFromContext(r.Context())) {
+		slog.Warn(fmt.Sprintf("rejecting a request for session %s from another user", id))
+		return nil, fmt.Errorf("unknown session %s", id)
+	}
+	if session.expired(s.idleTimeout) {
+		s.removeSession(id)
+		return nil, fmt.Errorf("unknown session %s", id)
+	}
+	session.lastSeen.Store(time.Now().UnixNano())
+	return session, nil
+}
+
+// expireSessions removes the sessions that had no request for the idle timeout.
+func (s *StreamableHTTPServer) expireSessions() {
+	s.sessions.Range(func(id, session any) bool {
+		if session.(*httpSession).expired(s.idleTimeout) {
+			s.removeSession(id.(string))
+		}
+		return true
+	})
+}
+
+func (s *StreamableHTTPServer) removeSession(id string) {
+	s.sessions.Delete(id)
+	s.server.UnregisterSession(id)
+}
+
+func writeEvent(w io.Writer, flusher http.Flusher, message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		slog.Error(fmt.Sprintf("failed to marshal event: %v", err))
+		return
+	}
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	flusher.Flush()
+}
+
+func writeHTTPError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(mcp.JSONRPCError{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Error: struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    any    `json:"data,omitempty"`
+		}{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestStreamableHTTPSessions(t *testing.T) {
+	alice := &User{Name: "alice", UID: "1"}
+	bob := &User{Name: "bob", UID: "2"}
+
+	post := func(h http.Handler, user *User, sessionID, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		if user != nil {
+			r = r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+		}
+		if sessionID != "" {
+			r.Header.Set(SessionIDHeader, sessionID)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+	ping := `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+
+	tests := []struct {
+		name        string
+		owner       *User
+		caller      *User
+		idleTimeout time.Duration
+		idle        time.Duration
+		want        int
+	}{
+		{name: "same user", owner: alice, caller: alice, want: http.StatusOK},
+		{name: "same user with other groups", owner: alice, caller: &User{Name: "alice", UID: "1", Groups: []string{"dev"}}, want: http.StatusOK},
+		{name: "other user", owner: alice, caller: bob, want: http.StatusNotFound},
+		{name: "unauthenticated caller", owner: alice, want: http.StatusNotFound},
+		{name: "without authentication", want: http.StatusOK},
+		{name: "idle", owner: alice, caller: alice, idleTimeout: time.Minute, idle: 2 * time.Minute, want: http.StatusNotFound},
+		{name: "not idle for long", owner: alice, caller: alice, idleTimeout: time.Minute, idle: 30 * time.Second, want: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewStreamableHTTPServer(server.NewMCPServer("test", "1"), "/mcp", nil)
+			if test.idleTimeout > 0 {
+				h.SetSessionIdleTimeout(test.idleTimeout)
+			}
+
+			w := post(h, test.owner, "", initialize)
+			if w.Code != http.StatusOK {
+				t.Fatalf("initialize returned %d: %s", w.Code, w.Body)
+			}
+			id := w.Header().Get(SessionIDHeader)
+			if session, ok := h.sessions.Load(id); ok && test.idle > 0 {
+				session.(*httpSession).lastSeen.Store(time.Now().Add(-test.idle).UnixNano())
+			}
+
+			w = post(h, test.caller, id, ping)
+			if w.Code != test.want {
+				t.Errorf("ping returned %d, want %d: %s", w.Code, test.want, w.Body)
+			}
+			if _, ok := h.sessions.Load(id); ok != (test.idle == 0 || test.want == http.StatusOK) {
+				t.Errorf("session kept = %v", ok)
+			}
+		})
+	}
+}
+
+func TestStreamableHTTPSessionExpiry(t *testing.T) {
+	h := NewStreamableHTTPServer(server.NewMCPServer("test", "1"), "/mcp", nil)
+	h.SetSessionIdleTimeout(time.Minute)
+	initialize := func() string {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Header().Get(SessionIDHeader)
+	}
+
+	idle, streaming := initialize(), initialize()
+	for _, id := range []string{idle, streaming} {
+		session, _ := h.sessions.Load(id)
+		session.(*httpSession).lastSeen.Store(time.Now().Add(-time.Hour).UnixNano())
+	}
+	session, _ := h.sessions.Load(streaming)
+	session.(*httpSession).streaming.Store(true)
+
+	// Creating a session removes the idle ones
+	active := initialize()
+	for id, want := range map[string]bool{idle: false, streaming: true, active: true} {
+		if _, ok := h.sessions.Load(id); ok != want {
+			t.Errorf("session %s kept = %v, want %v", id, ok, want)
+		}
+	}
+}
+
+func TestSSESessions(t *testing.T) {
+	alice := &User{Name: "alice", UID: "1"}
+	bob := &User{Name: "bob", UID: "2"}
+	users := map[string]*User{"alice": alice, "bob": bob}
+
+	tests := []struct {
+		name   string
+		owner  string
+		caller string
+		want   int
+	}{
+		{name: "same user", owner: "alice", caller: "alice", want: http.StatusAccepted},
+		{name: "other user", owner: "alice", caller: "bob", want: http.StatusNotFound},
+		{name: "unauthenticated caller", owner: "alice", want: http.StatusNotFound},
+		{name: "without authentication", want: http.StatusAccepted},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sseServer := server.NewSSEServer(server.NewMCPServer("test", "1"))
+			noop := func(_ context.Context, _ string, message json.RawMessage) (json.RawMessage, mcp.JSONRPCMessage) {
+				return message, nil
+			}
+			handler := InterceptSSE(sseServer, nil, noop)
+			// The user is set by the authentication in front of the transport
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if user := users[r.Header.Get("X-User")]; user != nil {
+					r = r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+				}
+				handler.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+
+			request := func(method, url, user string, body io.Reader) *http.Response {
+				r, err := http.NewRequest(method, url, body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.Header.Set("X-User", user)
+				resp, err := http.DefaultClient.Do(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return resp
+			}
+			stream := request(http.MethodGet, ts.URL+"/sse", test.owner, nil)
+			defer stream.Body.Close()
+			var endpoint string
+			scanner := bufio.NewScanner(stream.Body)
+			for scanner.Scan() {
+				if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+					endpoint = strings.TrimSpace(data)
+					break
+				}
+			}
+			if endpoint == "" {
+				t.Fatal("no endpoint event")
+			}
+
+			resp := request(http.MethodPost, ts.URL+endpoint, test.caller, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+			resp.Body.Close()
+			if resp.StatusCode != test.want {
+				t.Errorf("ping returned %d, want %d", resp.StatusCode, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
+	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/injection/clients/dynamicclient"
+)
+
+// triggersGroup is the API group of Tekton Triggers.
+const triggersGroup = "triggers.tekton.dev"
+
+// triggersSyncTimeout is how long the server waits for the Triggers informers to sync at startup.
+const triggersSyncTimeout = time.Minute
+
+// Labels set by Tekton Triggers on the objects created by an EventListener.
+const (
+	TriggersEventListenerLabelKey = "triggers.tekton.dev/eventlistener"
+	TriggersTriggerLabelKey       = "triggers.tekton.dev/trigger"
+	TriggersEventIDLabelKey       = "triggers.tekton.dev/triggers-eventid"
+)
+
+// triggersResources are the versions of the Triggers resources cached by the server.
+var triggersResources = map[string]schema.GroupVersionResource{
+	"eventlisteners":   {Group: triggersGroup, Version: "v1beta1", Resource: "eventlisteners"},
+	"triggers":         {Group: triggersGroup, Version: "v1beta1", Resource: "triggers"},
+	"triggerbindings":  {Group: triggersGroup, Version: "v1beta1", Resource: "triggerbindings"},
+	"triggertemplates": {Group: triggersGroup, Version: "v1beta1", Resource: "triggertemplates"},
+	"interceptors":     {Group: triggersGroup, Version: "v1alpha1", Resource: "interceptors"},
+}
+
+type triggersInformersKey struct{}
+
+// WithTriggersInformers starts the informers of the Triggers resources installed in the cluster and
+// adds them to the context. Triggers being optional, the resources that are not installed are
+// served from empty caches.
+func WithTriggersInformers(ctx context.Context) context.Context {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicclient.Get(ctx), 0)
+	discovery := kubeclient.Get(ctx).Discovery()
+
+	informers := map[string]cache.SharedIndexInformer{}
+	for resource, gvr := range triggersResources {
+		if !resourceInstalled(discovery.ServerResourcesForGroupVersion, gvr) {
+			slog.Info(fmt.Sprintf("%s are not installed in the cluster", gvr.GroupResource()))
+			continue
+		}
+		informers[resource] = factory.ForResource(gvr).Informer()
+	}
+	factory.Start(ctx.Done())
+
+	syncCtx, cancel := context.WithTimeout(ctx, triggersSyncTimeout)
+	defer cancel()
+	for gvr, synced := range factory.WaitForCacheSync(syncCtx.Done()) {
+		if !synced {
+			slog.Warn(fmt.Sprintf("the cache of %s is not synced yet", gvr.GroupResource()))
+		}
+	}
+	return context.WithValue(ctx, triggersInformersKey{}, informers)
+}
+
+func resourceInstalled(serverResources func(groupVersion string) (*metav1.APIResourceList, error), gvr schema.GroupVersionResource) bool {
+	resources, err := serverResources(gvr.GroupVersion().String())
+	if err != nil {
+		return false
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == gvr.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// triggersInformer returns the informer of a Triggers resource, or an informer that is never started
+// if the resource is not installed.
+func triggersInformer(ctx context.Context, resource string) cache.SharedIndexInformer {
+	informers, _ := ctx.Value(triggersInformersKey{}).(map[string]cache.SharedIndexInformer)
+	if informer, ok := informers[resource]; ok {
+		return informer
+	}
+	return cache.NewSharedIndexInformer(&cache.ListWatch{}, &unstructured.Unstructured{}, 0, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+	})
+}
+
+func toolGetRunTrigger() mcp.Tool {
+	return mcp.NewTool("get_run_trigger",
+		mcp.WithDescription("Identify the EventListener and Trigger that created a PipelineRun or TaskRun, from the labels set by Tekton Triggers"),
+		mcp.WithString("kind",
+			mcp.Description("Kind of the run, defaults to pipelinerun"),
+			mcp.Enum("pipelinerun", "taskrun"),
+		),
+		mcp.WithString("name", mcp.Required(),
+			mcp.Description("Name of the run"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace where the run is located, defaults to the server's default namespace"),
+		),
+	)
+}
+
+// triggerOrigin describes the EventListener and Trigger that created a run.
+type triggerOrigin struct {
+	Run              string `json:"run"`
+	EventID          string `json:"eventID,omitempty"`
+	EventListener    string `json:"eventListener"`
+	EventListenerURI string `json:"eventListenerURI,omitempty"`
+	Trigger          string `json:"trigger,omitempty"`
+	// TriggerURI is set when the trigger is a Trigger object, and not declared inline in the EventListener.
+	TriggerURI string `json:"triggerURI,omitempty"`
+	// TriggerSpec is the spec of the trigger, its interceptors, bindings and template.
+	TriggerSpec any      `json:"triggerSpec,omitempty"`
+	Notes       []string `json:"notes,omitempty"`
+}
+
+func handlerGetRunTrigger(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	kind, err := OptionalParam[string](request, "kind")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if kind == "" {
+		kind = "pipelinerun"
+	}
+	name, err := OptionalParam[string](request, "name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+	namespace, err := requestNamespace(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var run metav1.Object
+	switch kind {
+	case "pipelinerun":
+		run, err = pipelineruninformer.Get(ctx).Lister().PipelineRuns(namespace).Get(name)
+	case "taskrun":
+		run, err = taskruninformer.Get(ctx).Lister().TaskRuns(namespace).Get(name)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("kind %q is not one of pipelinerun or taskrun", kind)), nil
+	}
+	if err != nil {
+		return mcpError(fmt.Sprintf("Failed to get %s %s/%s: %v", kind, namespace, name, err)), nil
+	}
+
+	runLabels := run.GetLabels()
+	origin := triggerOrigin{
+		Run:           TektonResourceURI(kind, namespace, name),
+		EventID:       runLabels[TriggersEventIDLabelKey],
+		EventListener: runLabels[TriggersEventListenerLabelKey],
+		Trigger:       runLabels[TriggersTriggerLabelKey],
+	}
+	if origin.EventListener == "" {
+		return mcpError(fmt.Sprintf("%s %s/%s was not created by Tekton Triggers, it has no %s label", kind, namespace, name, TriggersEventListenerLabelKey)), nil
+	}
+
+	// The labels don't tell the namespace of the EventListener, which usually creates its runs in its own namespace
+	el, err := findTriggersObject(ctx, "eventlistener", namespace, origin.EventListener)
+	if err != nil {
+		origin.Notes = append(origin.Notes, err.Error())
+	}
+	if el != nil {
+		origin.EventListenerURI = TektonResourceURI("eventlistener", el.GetNamespace(), el.GetName())
+	}
+	if origin.Trigger == "" {
+		origin.Notes = append(origin.Notes, fmt.Sprintf("the run has no %s label", TriggersTriggerLabelKey))
+		return triggerOriginResult(origin)
+	}
+
+	// The trigger is either declared inline in the EventListener, or references a Trigger object
+	triggerRef := origin.Trigger
+	if el != nil {
+		triggers, _, _ := unstructured.NestedSlice(el.Object, "spec", "triggers")
+		for _, t := range triggers {
+			trigger, ok := t.(map[string]any)
+			if !ok {
+				continue
+			}
+			ref, _, _ := unstructured.NestedString(trigger, "triggerRef")
+			if trigger["name"] == origin.Trigger && ref == "" {
+				origin.TriggerSpec = trigger
+				return triggerOriginResult(origin)
+			}
+			if trigger["name"] == origin.Trigger || ref == origin.Trigger {
+				triggerRef = cmp.Or(ref, triggerRef)
+				break
+			}
+		}
+	}
+	triggerNamespace := namespace
+	if el != nil {
+		triggerNamespace = el.GetNamespace()
+	}
+	trigger, err := findTriggersObject(ctx, "trigger", triggerNamespace, triggerRef)
+	if err != nil {
+		origin.Notes = append(origin.Notes, err.Error())
+	}
+	if trigger != nil {
+		origin.TriggerURI = TektonResourceURI("trigger", trigger.GetNamespace(), trigger.GetName())
+		origin.TriggerSpec = trigger.Object["spec"]
+	}
+	return triggerOriginResult(origin)
+}
+
+// findTriggersObject returns the named object of a Triggers kind, looked up first in the given
+// namespace and then in every namespace the caller is allowed to list.
+func findTriggersObject(ctx context.Context, resourceType, namespace, name string) (*unstructured.Unstructured, error) {
+	k, _ := listKindOf(resourceType)
+	cfg := ConfigFromContext(ctx)
+	canList := listAuthorizer(ctx)
+
+	objs, err := listCached(k.informer(ctx), "", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var found *unstructured.Unstructured
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok || u.GetName() != name || !cfg.Namespaces.Allowed(u.GetNamespace()) || !canList(k, u.GetNamespace()) {
+			continue
+		}
+		if u.GetNamespace() == namespace {
+			return u, nil
+		}
+		if found == nil || u.GetNamespace() < found.GetNamespace() {
+			found = u
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s %s was not found in the namespaces the server can see", k.kind, name)
+	}
+	return found, nil
+}
+
+func triggerOriginResult(origin triggerOrigin) (*mcp.CallToolResult, error) {
+	jsonData, err := json.Marshal(origin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource to JSON: %w", err)
+	}
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
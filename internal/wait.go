@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
+	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// defaultWaitTimeout is how long the start tools wait for a run to finish when no wait-timeout is given.
+const defaultWaitTimeout = 10 * time.Minute
+
+// resyncInterval is how often a waited run is re-checked even if no informer event was received.
+const resyncInterval = 5 * time.Second
+
+// runWatcher is notified when objects watched through informers change.
+type runWatcher struct {
+	changes       chan struct{}
+	registrations []func()
+}
+
+func newRunWatcher() *runWatcher {
+	return &runWatcher{changes: make(chan struct{}, 1)}
+}
+
+// watch registers an event handler on the informer for the objects matched by filter.
+func (w *runWatcher) watch(informer cache.SharedIndexInformer, filter func(metav1.Object) bool) error {
+	notify := func(obj any) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		o, err := meta.Accessor(obj)
+		if err != nil || !filter(o) {
+			return
+		}
+		select {
+		case w.changes <- struct{}{}:
+		default:
+		}
+	}
+
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj any) { notify(obj) },
+		DeleteFunc: notify,
+	})
+	if err != nil {
+		return err
+	}
+	w.registrations = append(w.registrations, func() {
+		if err := informer.RemoveEventHandler(registration); err != nil {
+			slog.Warn(fmt.Sprintf("failed to remove event handler: %v", err))
+		}
+	})
+	return nil
+}
+
+func (w *runWatcher) stop() {
+	for _, unregister := range w.registrations {
+		unregister()
+	}
+}
+
+// wait calls check each time a watched object changes, until it returns true, the timeout expires
+// or ctx is done. It returns false if the run didn't finish in time.
+func (w *runWatcher) wait(ctx context.Context, timeout time.Duration, check func() bool) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if check() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-w.changes:
+		case <-ticker.C:
+		}
+	}
+}
+
+// progressReporter sends MCP progress notifications to the client if it asked for them.
+type progressReporter struct {
+	ctx      context.Context
+	token    mcp.ProgressToken
+	progress int
+	last     map[string]string
+}
+
+func newProgressReporter(ctx context.Context, request mcp.CallToolRequest) *progressReporter {
+	p := &progressReporter{ctx: ctx, last: map[string]string{}}
+	if request.Params.Meta != nil {
+		p.token = request.Params.Meta.ProgressToken
+	}
+	return p
+}
+
+// update reports the state of the given item if it changed since the last update.
+func (p *progressReporter) update(item, state string, done, total int) {
+	if p.last[item] == state {
+		return
+	}
+	p.last[item] = state
+	p.progress++
+
+	if p.token == nil {
+		return
+	}
+	s := server.ServerFromContext(p.ctx)
+	if s == nil {
+		return
+	}
+	params := map[string]any{
+		"progressToken": p.token,
+		"progress":      p.progress,
+		"message":       fmt.Sprintf("%s: %s (%d/%d done)", item, state, done, total),
+	}
+	if err := s.SendNotificationToClient(p.ctx, "notifications/progress", params); err != nil {
+		slog.Warn(fmt.Sprintf("failed to send progress notification: %v", err))
+	}
+}
+
+// waitParams reads the "wait" and "wait-timeout" tool parameters.
+func waitParams(request mcp.CallToolRequest) (bool, time.Duration, error) {
+	wait, err := OptionalParam[bool](request, "wait")
+	if err != nil {
+		return false, 0, err
+	}
+	timeout := defaultWaitTimeout
+	t, err := OptionalParam[string](request, "wait-timeout")
+	if err != nil {
+		return false, 0, err
+	}
+	if t != "" {
+		timeout, err = time.ParseDuration(t)
+		if err != nil {
+			return false, 0, fmt.Errorf("parameter wait-timeout is not a valid duration: %w", err)
+		}
+	}
+	return wait, timeout, nil
+}
+
+// waitForPipelineRun waits for the PipelineRun to finish, reporting the progress of its child TaskRuns
+// and CustomRuns.
+func waitForPipelineRun(ctx context.Context, request mcp.CallToolRequest, namespace, name string, timeout time.Duration) (*v1.PipelineRun, bool, error) {
+	pipelineRunInformer := pipelineruninformer.Get(ctx)
+	taskRunInformer := taskruninformer.Get(ctx)
+	customRunInformer := customruninformer.Get(ctx)
+
+	watcher := newRunWatcher()
+	defer watcher.stop()
+	if err := watcher.watch(pipelineRunInformer.Informer(), func(o metav1.Object) bool {
+		return o.GetNamespace() == namespace && o.GetName() == name
+	}); err != nil {
+		return nil, false, err
+	}
+	for _, informer := range []cache.SharedIndexInformer{taskRunInformer.Informer(), customRunInformer.Informer()} {
+		if err := watcher.watch(informer, func(o metav1.Object) bool {
+			return o.GetNamespace() == namespace && o.GetLabels()[pipeline.PipelineRunLabelKey] == name
+		}); err != nil {
+			return nil, false, err
+		}
+	}
+
+	progress := newProgressReporter(ctx, request)
+	var pr *v1.PipelineRun
+	done := watcher.wait(ctx, timeout, func() bool {
+		current, err := pipelineRunInformer.Lister().PipelineRuns(namespace).Get(name)
+		if err != nil {
+			// Not in the informer cache yet
+			return false
+		}
+		pr = current
+
+		outcome := pipelineRunOutcome(ctx, pr)
+		total := len(outcome.TaskRuns)
+		if pr.Status.PipelineSpec != nil {
+			total = len(pr.Status.PipelineSpec.Tasks) + len(pr.Status.PipelineSpec.Finally)
+		}
+		finished := 0
+		for _, child := range outcome.TaskRuns {
+			if child.Status != "" && child.Status != "Unknown" {
+				finished++
+			}
+		}
+		for _, child := range outcome.TaskRuns {
+			progress.update(fmt.Sprintf("%s %s (%s)", child.Kind, child.Name, child.PipelineTask), childState(child.Status, child.Reason), finished, total)
+		}
+
+		return pr.IsDone()
+	})
+	if pr == nil {
+		return nil, false, fmt.Errorf("PipelineRun %s/%s was not observed before the timeout", namespace, name)
+	}
+	return pr, done, nil
+}
+
+// waitForTaskRun waits for the TaskRun to finish, reporting the progress of its steps.
+func waitForTaskRun(ctx context.Context, request mcp.CallToolRequest, namespace, name string, timeout time.Duration) (*v1.TaskRun, bool, error) {
+	taskRunInformer := taskruninformer.Get(ctx)
+
+	watcher := newRunWatcher()
+	defer watcher.stop()
+	if err := watcher.watch(taskRunInformer.Informer(), func(o metav1.Object) bool {
+		return o.GetNamespace() == namespace && o.GetName() == name
+	}); err != nil {
+		return nil, false, err
+	}
+
+	progress := newProgressReporter(ctx, request)
+	var tr *v1.TaskRun
+	done := watcher.wait(ctx, timeout, func() bool {
+		current, err := taskRunInformer.Lister().TaskRuns(namespace).Get(name)
+		if err != nil {
+			// Not in the informer cache yet
+			return false
+		}
+		tr = current
+
+		outcome := taskRunOutcome(tr)
+		finished := 0
+		for _, step := range outcome.Steps {
+			if step.State == "terminated" {
+				finished++
+			}
+		}
+		for _, step := range outcome.Steps {
+			progress.update(fmt.Sprintf("step %s", step.Name), step.State, finished, len(outcome.Steps))
+		}
+
+		return tr.IsDone()
+	})
+	if tr == nil {
+		return nil, false, fmt.Errorf("TaskRun %s/%s was not observed before the timeout", namespace, name)
+	}
+	return tr, done, nil
+}
+
+// waitForCustomRun waits for the CustomRun to finish.
+func waitForCustomRun(ctx context.Context, namespace, name string, timeout time.Duration) (*v1beta1.CustomRun, bool, error) {
+	customRunInformer := customruninformer.Get(ctx)
+
+	watcher := newRunWatcher()
+	defer watcher.stop()
+	if err := watcher.watch(customRunInformer.Informer(), func(o metav1.Object) bool {
+		return o.GetNamespace() == namespace && o.GetName() == name
+	}); err != nil {
+		return nil, false, err
+	}
+
+	var cr *v1beta1.CustomRun
+	done := watcher.wait(ctx, timeout, func() bool {
+		current, err := customRunInformer.Lister().CustomRuns(namespace).Get(name)
+		if err != nil {
+			// Not in the informer cache yet
+			return false
+		}
+		cr = current
+		return cr.IsDone()
+	})
+	if cr == nil {
+		return nil, false, fmt.Errorf("CustomRun %s/%s was not observed before the timeout", namespace, name)
+	}
+	return cr, done, nil
+}
+
+func childState(status, reason string) string {
+	switch {
+	case reason != "":
+		return reason
+	case status != "":
+		return status
+	default:
+		return "Pending"
+	}
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	tektonscheme "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// objectScheme knows the kinds of the objects rendered as YAML, to set their apiVersion and kind
+// which the informers leave empty.
+var objectScheme = runtime.NewScheme()
+
+func init() {
+	if err := tektonscheme.AddToScheme(objectScheme); err != nil {
+		panic(err)
+	}
+}
+
+// objectYAML renders an object like kubectl get -o yaml, with its apiVersion and kind and without
+// its managedFields.
+func objectYAML(obj runtime.Object) ([]byte, error) {
+	obj = obj.DeepCopyObject()
+	gvks, _, err := objectScheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the kind of %T: %w", obj, err)
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+	if o, ok := obj.(metav1.Object); ok {
+		o.SetManagedFields(nil)
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource to YAML: %w", err)
+	}
+	return data, nil
+}
+
+// objectsYAML renders objects as a stream of YAML documents.
+func objectsYAML[T metav1.Object](objs []T) (string, error) {
+	docs := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		o, ok := any(obj).(runtime.Object)
+		if !ok {
+			return "", fmt.Errorf("cannot render %T as YAML", obj)
+		}
+		data, err := objectYAML(o)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(data))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
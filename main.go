@@ -1,16 +1,22 @@
 package main
 
 import (
-	// "context"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"path"
+	"strings"
+	"time"
 
 	// "github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/openshift-pipelines/mcp-tekton/internal"
 	"k8s.io/client-go/tools/clientcmd"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	filteredinformerfactory "knative.dev/pkg/client/injection/kube/informers/factory/filtered"
 	"knative.dev/pkg/injection"
 	"knative.dev/pkg/signals"
@@ -19,9 +25,33 @@ import (
 // ManagedByLabelKey is the label key used to mark what is managing this resource
 const ManagedByLabelKey = "app.kubernetes.io/managed-by"
 
+var (
+	transport     = flag.String("transport", "stdio", "Transport to serve the MCP server on: stdio, sse or http (streamable HTTP)")
+	listenAddress = flag.String("listen-address", ":8080", "Address to listen on for the sse and http transports")
+	basePath      = flag.String("base-path", "", "Base path of the endpoints of the sse and http transports")
+	baseURL       = flag.String("base-url", "", "Public URL of the server, used by the sse transport to advertise its message endpoint")
+	tlsCert       = flag.String("tls-cert", "", "TLS certificate file for the sse and http transports")
+	tlsKey        = flag.String("tls-key", "", "TLS key file for the sse and http transports")
+	tokenFile     = flag.String("auth-token-file", "", "File of bearer tokens accepted by the sse and http transports, one token,user,uid,\"group1,group2\" per line")
+	tokenReview   = flag.Bool("auth-token-review", false, "Validate the bearer tokens of the sse and http transports with a Kubernetes TokenReview")
+	configFile    = flag.String("config", "", "Configuration file listing the allowed and denied tools and namespaces")
+	readOnly      = flag.Bool("read-only", false, "Only expose the tools that don't create or modify cluster objects")
+	audiences     = flag.String("auth-audiences", "", "Comma-separated audiences the bearer tokens must be valid for, when validated with a TokenReview")
+	resultsURL    = flag.String("results-url", "", "URL of the Tekton Results API, to read the runs and logs that are no longer in the cluster")
+	resultsCAFile = flag.String("results-ca-file", "", "CA bundle of the Tekton Results API certificate, defaults to the system roots")
+	sessionIdle   = flag.Duration("session-idle-timeout", 30*time.Minute, "How long a session of the http transport is kept without requests")
+)
+
 func main() {
-	// hooks := &server.Hooks{}
-	//
+	flag.Parse()
+
+	hooks := &server.Hooks{}
+	// resources/list enumerates the objects of the informer caches
+	hooks.AddAfterListResources(internal.ListCachedResources)
+	// resources/subscribe is handled by the transports, notifying the sessions of the changes
+	subscriptions := internal.NewSubscriptions()
+	hooks.AddOnRegisterSession(subscriptions.RegisterSession)
+
 	// hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
 	// 	fmt.Printf("beforeAny: %s, %v, %v\n", method, id, message)
 	// })
@@ -44,6 +74,17 @@ func main() {
 	// 	fmt.Printf("beforeCallTool: %v, %v\n", id, message)
 	// })
 
+	serverConfig := &internal.Config{}
+	if *configFile != "" {
+		c, err := internal.LoadConfig(*configFile)
+		if err != nil {
+			slog.Error(fmt.Sprintf("failed to load configuration: %v", err))
+			os.Exit(1)
+		}
+		serverConfig = c
+	}
+	serverConfig.ReadOnly = serverConfig.ReadOnly || *readOnly
+
 	// Create MCP server
 	s := server.NewMCPServer(
 		"Tekton",
@@ -52,7 +93,9 @@ func main() {
 		server.WithPromptCapabilities(true),
 		server.WithToolCapabilities(true),
 		server.WithLogging(),
-		// server.WithHooks(hooks),
+		server.WithToolHandlerMiddleware(serverConfig.RestrictToolCall),
+		server.WithToolHandlerMiddleware(internal.AuthorizeToolCall),
+		server.WithHooks(hooks),
 	)
 
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
@@ -63,8 +106,18 @@ func main() {
 		slog.Error(fmt.Sprintf("failed to get Kubernetes config: %v", err))
 		os.Exit(1)
 	}
+	if serverConfig.DefaultNamespace == "" {
+		// The namespace of the kubeconfig context, or default
+		namespace, _, err := kubeConfig.Namespace()
+		if err != nil {
+			slog.Error(fmt.Sprintf("failed to get the namespace of the Kubernetes context: %v", err))
+			os.Exit(1)
+		}
+		serverConfig.DefaultNamespace = namespace
+	}
 
 	ctx := signals.NewContext()
+	ctx = internal.WithConfig(ctx, serverConfig)
 	ctx = filteredinformerfactory.WithSelectors(ctx, ManagedByLabelKey)
 	// slog.Info("Registering %d informer factories", len(injection.Default.GetInformerFactories()))
 	// slog.Info("Registering %d informers", len(injection.Default.GetInformers()))
@@ -72,25 +125,70 @@ func main() {
 
 	// Start the injection clients and informers.
 	startInformers()
+	ctx = internal.WithTriggersInformers(ctx)
+	if *resultsURL != "" {
+		results, err := internal.NewResultsClient(*resultsURL, *resultsCAFile, cfg)
+		if err != nil {
+			slog.Error(fmt.Sprintf("failed to configure Tekton Results: %v", err))
+			os.Exit(1)
+		}
+		ctx = internal.WithResults(ctx, results)
+	}
 
 	slog.Info("Addingtools, prompts, and resources to the server.")
-	internal.AddTools(s)
+	internal.AddTools(s, serverConfig)
 	internal.AddPrompts(s)
 	internal.AddResources(ctx, s)
+	if err := subscriptions.Start(ctx, s); err != nil {
+		slog.Error(fmt.Sprintf("failed to watch the resources: %v", err))
+		os.Exit(1)
+	}
+
+	// Callers of the network transports are authenticated and impersonated
+	authenticator := authenticators(ctx)
+	contextFunc := internal.WithImpersonation(cfg, internal.WithInjection(ctx))
+	withAuthentication := func(handler http.Handler) http.Handler {
+		if authenticator == nil {
+			slog.Warn("No authentication configured, every caller acts with the server identity.")
+			return handler
+		}
+		return internal.RequireBearerToken(handler, authenticator)
+	}
 
 	slog.Info("Starting the server.")
-	// Start the stdio server
-	stdioServer := server.NewStdioServer(s)
-	// Start listening for messages
 	errC := make(chan error, 1)
-	go func() {
-		in, out := io.Reader(os.Stdin), io.Writer(os.Stdout)
+	switch *transport {
+	case "stdio":
+		// Start the stdio server
+		stdioServer := server.NewStdioServer(s)
+		// Start listening for messages
+		go func() {
+			in, out := internal.InterceptStdio(ctx, os.Stdin, os.Stdout, subscriptions.Intercept)
 
-		errC <- stdioServer.Listen(ctx, in, out)
-	}()
+			errC <- stdioServer.Listen(ctx, in, out)
+		}()
 
-	// Output tekton-mcp string
-	_, _ = fmt.Fprintf(os.Stderr, "Tekton MCP Server running on stdio\n")
+		// Output tekton-mcp string
+		_, _ = fmt.Fprintf(os.Stderr, "Tekton MCP Server running on stdio\n")
+	case "sse":
+		sseServer := server.NewSSEServer(s,
+			server.WithBaseURL(*baseURL),
+			server.WithBasePath(*basePath),
+			server.WithSSEContextFunc(server.SSEContextFunc(contextFunc)),
+			server.WithKeepAlive(true),
+		)
+		go serveHTTP(ctx, withAuthentication(internal.InterceptSSE(sseServer, contextFunc, subscriptions.Intercept)), errC)
+		slog.Info(fmt.Sprintf("Tekton MCP Server running on %s with SSE on %s", *listenAddress, sseServer.CompleteSsePath()))
+	case "http":
+		httpServer := internal.NewStreamableHTTPServer(s, path.Join("/", *basePath, "mcp"), contextFunc)
+		httpServer.SetInterceptor(subscriptions.Intercept)
+		httpServer.SetSessionIdleTimeout(*sessionIdle)
+		go serveHTTP(ctx, withAuthentication(httpServer), errC)
+		slog.Info(fmt.Sprintf("Tekton MCP Server running on %s with streamable HTTP on %s", *listenAddress, path.Join("/", *basePath, "mcp")))
+	default:
+		slog.Error(fmt.Sprintf("unknown transport %q, must be one of stdio, sse or http", *transport))
+		os.Exit(1)
+	}
 
 	// Wait for shutdown signal
 	select {
@@ -103,3 +201,55 @@ func main() {
 		}
 	}
 }
+
+// serveHTTP serves the handler on the listen address, with TLS if a certificate is configured,
+// until the context is done.
+func serveHTTP(ctx context.Context, handler http.Handler, errC chan<- error) {
+	srv := &http.Server{
+		Addr:              *listenAddress,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	var err error
+	if *tlsCert != "" || *tlsKey != "" {
+		err = srv.ListenAndServeTLS(*tlsCert, *tlsKey)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	errC <- err
+}
+
+// authenticators returns the configured bearer token authenticators, or nil if none is configured.
+func authenticators(ctx context.Context) internal.Authenticator {
+	var authenticators internal.Authenticators
+	if *tokenFile != "" {
+		a, err := internal.NewStaticTokenAuthenticator(*tokenFile)
+		if err != nil {
+			slog.Error(fmt.Sprintf("failed to load token file: %v", err))
+			os.Exit(1)
+		}
+		authenticators = append(authenticators, a)
+	}
+	if *tokenReview {
+		var tokenAudiences []string
+		if *audiences != "" {
+			tokenAudiences = strings.Split(*audiences, ",")
+		}
+		authenticators = append(authenticators, internal.NewTokenReviewAuthenticator(kubeclient.Get(ctx), tokenAudiences))
+	}
+	if len(authenticators) == 0 {
+		return nil
+	}
+	return authenticators
+}